@@ -40,6 +40,10 @@ MMBitmapRef createMMBitmap(uint8_t *buffer, size_t width, size_t height,
 /* Releases memory occupied by MMBitmap. */
 void destroyMMBitmap(MMBitmapRef bitmap);
 
+/* Returns the count of the live MMBitmaps, created and not yet destroyed;
+ * for the leak checks. */
+long liveMMBitmapCount(void);
+
 /* Releases memory occupied by MMBitmap. Acts via CallBack method*/
 void destroyMMBitmapBuffer(char * bitmapBuffer, void * hint);
 
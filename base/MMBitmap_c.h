@@ -3,6 +3,15 @@
 #include <string.h>
 
 
+/* The count of the live MMBitmaps, the captures may run on the
+ * other threads. */
+static long mmBitmapLive = 0;
+
+long liveMMBitmapCount(void)
+{
+	return __sync_add_and_fetch(&mmBitmapLive, 0);
+}
+
 //MMBitmapRef createMMBitmap()
 MMBitmapRef createMMBitmap(
 	uint8_t *buffer,
@@ -21,6 +30,7 @@ MMBitmapRef createMMBitmap(
 	bitmap->bytewidth = bytewidth;
 	bitmap->bitsPerPixel = bitsPerPixel;
 	bitmap->bytesPerPixel = bytesPerPixel;
+	__sync_add_and_fetch(&mmBitmapLive, 1);
 
 	return bitmap;
 }
@@ -35,6 +45,7 @@ void destroyMMBitmap(MMBitmapRef bitmap)
 	}
 
 	free(bitmap);
+	__sync_sub_and_fetch(&mmBitmapLive, 1);
 }
 
 void destroyMMBitmapBuffer(char * bitmapBuffer, void * hint)
@@ -29,6 +29,17 @@ struct _MMRect {
 
 typedef struct _MMRect MMRect;
 
+/* The rect of the virtual desktop, the origin may be left of or
+ * above the main display. */
+struct _MMSignedRect {
+	int x;
+	int y;
+	int width;
+	int height;
+};
+
+typedef struct _MMSignedRect MMSignedRect;
+
 H_INLINE MMPoint MMPointMake(size_t x, size_t y)
 {
 	MMPoint point;
@@ -53,6 +64,16 @@ H_INLINE MMRect MMRectMake(size_t x, size_t y, size_t width, size_t height)
 	return rect;
 }
 
+H_INLINE MMSignedRect MMSignedRectMake(int x, int y, int width, int height)
+{
+	MMSignedRect rect;
+	rect.x = x;
+	rect.y = y;
+	rect.width = width;
+	rect.height = height;
+	return rect;
+}
+
 #define MMPointZero MMPointMake(0, 0)
 
 #if defined(IS_MACOSX)
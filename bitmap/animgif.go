@@ -0,0 +1,156 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"bufio"
+	"compress/lzw"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"io"
+	"time"
+)
+
+// gifWriter streams the animated gif, a frame is the changed rect
+// of the previous frame with its own color table
+type gifWriter struct {
+	w  io.Writer
+	bw *bufio.Writer
+}
+
+// gifDelay returns the delay in 1/100s, min 2 as the browsers
+func gifDelay(d time.Duration) int {
+	cs := int((d + 5*time.Millisecond) / (10 * time.Millisecond))
+	if cs < 2 {
+		return 2
+	}
+	if cs > 0xffff {
+		return 0xffff
+	}
+
+	return cs
+}
+
+func (g *gifWriter) frame(bit, prev *Bitmap, delay time.Duration) error {
+	if prev == nil {
+		if bit.Width > 0xffff || bit.Height > 0xffff {
+			return ErrVideoSize
+		}
+
+		g.bw = bufio.NewWriter(g.w)
+		// the logical screen without the global color table,
+		// the NETSCAPE2.0 loop forever
+		g.bw.WriteString("GIF89a")
+		g.bw.Write([]byte{byte(bit.Width), byte(bit.Width >> 8),
+			byte(bit.Height), byte(bit.Height >> 8), 0, 0, 0})
+		g.bw.Write([]byte{0x21, 0xff, 0x0b})
+		g.bw.WriteString("NETSCAPE2.0")
+		g.bw.Write([]byte{0x03, 0x01, 0, 0, 0})
+	}
+
+	r := changedRect(bit, prev)
+	sub := rgbaRect(bit, r)
+	p := paletted(sub)
+	if p == nil {
+		p = image.NewPaletted(r, palette.Plan9)
+		draw.FloydSteinberg.Draw(p, r, sub, r.Min)
+	}
+
+	// the graphic control, not disposed
+	cs := gifDelay(delay)
+	g.bw.Write([]byte{0x21, 0xf9, 0x04, 0x04, byte(cs), byte(cs >> 8), 0, 0})
+
+	bits := 1
+	for 1<<uint(bits) < len(p.Palette) {
+		bits++
+	}
+	g.bw.Write([]byte{0x2c, byte(r.Min.X), byte(r.Min.X >> 8),
+		byte(r.Min.Y), byte(r.Min.Y >> 8), byte(r.Dx()), byte(r.Dx() >> 8),
+		byte(r.Dy()), byte(r.Dy() >> 8), 0x80 | byte(bits-1)})
+
+	table := make([]byte, 3<<uint(bits))
+	for i, c := range p.Palette {
+		cr, cg, cb, _ := c.RGBA()
+		table[i*3], table[i*3+1], table[i*3+2] = byte(cr>>8), byte(cg>>8), byte(cb>>8)
+	}
+	g.bw.Write(table)
+
+	lit := bits
+	if lit < 2 {
+		lit = 2
+	}
+	g.bw.WriteByte(byte(lit))
+
+	bw := &blockWriter{w: g.bw}
+	lw := lzw.NewWriter(bw, lzw.LSB, lit)
+	for y := 0; y < r.Dy(); y++ {
+		if _, err := lw.Write(p.Pix[y*p.Stride : y*p.Stride+r.Dx()]); err != nil {
+			return err
+		}
+	}
+	if err := lw.Close(); err != nil {
+		return err
+	}
+
+	return bw.close()
+}
+
+func (g *gifWriter) close() error {
+	if g.bw == nil {
+		return nil
+	}
+
+	g.bw.WriteByte(0x3b)
+	return g.bw.Flush()
+}
+
+// blockWriter writes the gif data sub-blocks, max 255 bytes
+type blockWriter struct {
+	w   *bufio.Writer
+	buf [256]byte
+	n   int
+}
+
+func (b *blockWriter) Write(p []byte) (int, error) {
+	for i := range p {
+		b.n++
+		b.buf[b.n] = p[i]
+		if b.n == 255 {
+			if err := b.flush(); err != nil {
+				return i, err
+			}
+		}
+	}
+
+	return len(p), nil
+}
+
+func (b *blockWriter) flush() error {
+	if b.n == 0 {
+		return nil
+	}
+
+	b.buf[0] = byte(b.n)
+	_, err := b.w.Write(b.buf[:b.n+1])
+	b.n = 0
+
+	return err
+}
+
+// close write the last sub-block and the block terminator
+func (b *blockWriter) close() error {
+	if err := b.flush(); err != nil {
+		return err
+	}
+
+	return b.w.WriteByte(0)
+}
@@ -0,0 +1,152 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image/png"
+	"io"
+	"time"
+)
+
+// pngHeader is the png file signature
+const pngHeader = "\x89PNG\r\n\x1a\n"
+
+// apngWriter streams the animated png, a frame is the changed
+// rect of the previous frame; the frames count of acTL is
+// updated on close
+type apngWriter struct {
+	w      io.WriteSeeker
+	enc    png.Encoder
+	buf    bytes.Buffer
+	seq    uint32
+	frames uint32
+}
+
+// writeChunk write the png chunk with its crc
+func writeChunk(w io.Writer, typ string, data []byte) error {
+	chunk := make([]byte, 12+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	copy(chunk[4:], typ)
+	copy(chunk[8:], data)
+	binary.BigEndian.PutUint32(chunk[8+len(data):],
+		crc32.ChecksumIEEE(chunk[4:8+len(data)]))
+
+	_, err := w.Write(chunk)
+	return err
+}
+
+// pngChunks returns the IHDR and the joined IDAT data of the png
+func pngChunks(data []byte) (ihdr, idat []byte) {
+	for p := len(pngHeader); p+12 <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[p:]))
+		if p+12+n > len(data) {
+			break
+		}
+
+		switch string(data[p+4 : p+8]) {
+		case "IHDR":
+			ihdr = data[p+8 : p+8+n]
+		case "IDAT":
+			idat = append(idat, data[p+8:p+8+n]...)
+		}
+		p += 12 + n
+	}
+
+	return
+}
+
+// apngDelay returns the delay fraction, num / den seconds
+func apngDelay(d time.Duration) (uint16, uint16) {
+	num, den := int64(d/time.Millisecond), int64(1000)
+	for num > 0xffff && den > 1 {
+		num, den = num/10, den/10
+	}
+	if num > 0xffff {
+		num = 0xffff
+	}
+
+	return uint16(num), uint16(den)
+}
+
+func (a *apngWriter) frame(bit, prev *Bitmap, delay time.Duration) error {
+	r := changedRect(bit, prev)
+
+	a.buf.Reset()
+	a.enc.CompressionLevel = png.BestSpeed
+	if err := a.enc.Encode(&a.buf, rgbaRect(bit, r)); err != nil {
+		return err
+	}
+	ihdr, idat := pngChunks(a.buf.Bytes())
+
+	if prev == nil {
+		if _, err := io.WriteString(a.w, pngHeader); err != nil {
+			return err
+		}
+		if err := writeChunk(a.w, "IHDR", ihdr); err != nil {
+			return err
+		}
+		// the frames count is updated on close
+		if err := writeChunk(a.w, "acTL", make([]byte, 8)); err != nil {
+			return err
+		}
+	}
+
+	// not disposed, the source replaces the rect
+	num, den := apngDelay(delay)
+	fctl := make([]byte, 26)
+	binary.BigEndian.PutUint32(fctl, a.seq)
+	binary.BigEndian.PutUint32(fctl[4:], uint32(r.Dx()))
+	binary.BigEndian.PutUint32(fctl[8:], uint32(r.Dy()))
+	binary.BigEndian.PutUint32(fctl[12:], uint32(r.Min.X))
+	binary.BigEndian.PutUint32(fctl[16:], uint32(r.Min.Y))
+	binary.BigEndian.PutUint16(fctl[20:], num)
+	binary.BigEndian.PutUint16(fctl[22:], den)
+	a.seq++
+	if err := writeChunk(a.w, "fcTL", fctl); err != nil {
+		return err
+	}
+
+	a.frames++
+	if prev == nil {
+		return writeChunk(a.w, "IDAT", idat)
+	}
+
+	fdat := make([]byte, 4, 4+len(idat))
+	binary.BigEndian.PutUint32(fdat, a.seq)
+	a.seq++
+
+	return writeChunk(a.w, "fdAT", append(fdat, idat...))
+}
+
+func (a *apngWriter) close() error {
+	if a.frames == 0 {
+		return nil
+	}
+	if err := writeChunk(a.w, "IEND", nil); err != nil {
+		return err
+	}
+
+	// the acTL after the IHDR, play forever
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl, a.frames)
+	if _, err := a.w.Seek(int64(len(pngHeader)+12+13), io.SeekStart); err != nil {
+		return err
+	}
+	if err := writeChunk(a.w, "acTL", actl); err != nil {
+		return err
+	}
+
+	_, err := a.w.Seek(0, io.SeekEnd)
+	return err
+}
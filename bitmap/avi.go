@@ -0,0 +1,188 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/jpeg"
+	"io"
+	"math"
+	"time"
+)
+
+// The AVI layout: the RIFF, hdrl and movi headers size and
+// the offset of the "movi" fourcc, the idx1 offsets base
+const (
+	aviHeaderSize = 224
+	aviMoviOffset = 220
+)
+
+// aviIndex is the idx1 entry of a frame
+type aviIndex struct {
+	offset, size uint32
+	key          bool
+}
+
+// aviWriter streams the mjpeg avi 1.0 at a constant frame rate,
+// the longer delays are the zero size frames (the previous frame
+// is repeated); the headers and idx1 are written on close
+type aviWriter struct {
+	w       io.WriteSeeker
+	fps     float64
+	quality int
+
+	width, height int
+	pos           int64
+	maxSize       int
+	index         []aviIndex
+	buf           bytes.Buffer
+}
+
+// chunk write the "00dc" frame chunk, padded to even
+func (a *aviWriter) chunk(data []byte) error {
+	head := make([]byte, 8)
+	copy(head, "00dc")
+	binary.LittleEndian.PutUint32(head[4:], uint32(len(data)))
+	if len(data)%2 == 1 {
+		data = append(data, 0)
+	}
+
+	if a.pos+8+int64(len(data)) > math.MaxUint32-aviHeaderSize {
+		return ErrVideoSize
+	}
+	if _, err := a.w.Write(head); err != nil {
+		return err
+	}
+	if _, err := a.w.Write(data); err != nil {
+		return err
+	}
+
+	a.index = append(a.index, aviIndex{offset: uint32(a.pos + aviHeaderSize -
+		aviMoviOffset), size: binary.LittleEndian.Uint32(head[4:]), key: len(data) > 0})
+	a.pos += 8 + int64(len(data))
+
+	return nil
+}
+
+func (a *aviWriter) frame(bit, prev *Bitmap, delay time.Duration) error {
+	if prev == nil {
+		a.width, a.height = bit.Width, bit.Height
+		// the counts and sizes are updated on close
+		if _, err := a.w.Write(a.header()); err != nil {
+			return err
+		}
+	}
+
+	a.buf.Reset()
+	if err := jpeg.Encode(&a.buf, bit.ToRGBA(),
+		&jpeg.Options{Quality: a.quality}); err != nil {
+		return err
+	}
+	if a.buf.Len() > a.maxSize {
+		a.maxSize = a.buf.Len()
+	}
+	if err := a.chunk(a.buf.Bytes()); err != nil {
+		return err
+	}
+
+	n := int(math.Floor(delay.Seconds()*a.fps + 0.5))
+	for i := 1; i < n; i++ {
+		if err := a.chunk(nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *aviWriter) close() error {
+	if len(a.index) == 0 {
+		return nil
+	}
+
+	idx := make([]byte, 8+16*len(a.index))
+	copy(idx, "idx1")
+	binary.LittleEndian.PutUint32(idx[4:], uint32(16*len(a.index)))
+	for i, e := range a.index {
+		p := idx[8+i*16:]
+		copy(p, "00dc")
+		if e.key {
+			// AVIIF_KEYFRAME
+			binary.LittleEndian.PutUint32(p[4:], 0x10)
+		}
+		binary.LittleEndian.PutUint32(p[8:], e.offset)
+		binary.LittleEndian.PutUint32(p[12:], e.size)
+	}
+
+	if a.pos+aviHeaderSize+int64(len(idx)) > math.MaxUint32 {
+		return ErrVideoSize
+	}
+	if _, err := a.w.Write(idx); err != nil {
+		return err
+	}
+	a.pos += int64(len(idx))
+
+	if _, err := a.w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := a.w.Write(a.header()); err != nil {
+		return err
+	}
+
+	_, err := a.w.Seek(0, io.SeekEnd)
+	return err
+}
+
+// header returns the RIFF, hdrl and movi headers of the
+// written frames
+func (a *aviWriter) header() []byte {
+	var (
+		buf    bytes.Buffer
+		frames = uint32(len(a.index))
+		size   = uint32(a.maxSize)
+		moviSz = uint32(a.pos) + 4
+	)
+	if len(a.index) > 0 {
+		// the idx1 is after the movi list
+		moviSz -= 8 + 16*frames
+	}
+
+	put := func(vals ...interface{}) {
+		for _, v := range vals {
+			if s, ok := v.(string); ok {
+				buf.WriteString(s)
+				continue
+			}
+			binary.Write(&buf, binary.LittleEndian, v)
+		}
+	}
+
+	put("RIFF", uint32(aviHeaderSize-8)+uint32(a.pos), "AVI ",
+		"LIST", uint32(192), "hdrl")
+	// avih: the main header, AVIF_HASINDEX
+	put("avih", uint32(56), uint32(math.Floor(1e6/a.fps+0.5)),
+		uint32(float64(size)*a.fps), uint32(0), uint32(0x10), frames,
+		uint32(0), uint32(1), size, uint32(a.width), uint32(a.height),
+		[4]uint32{})
+	// strh: the mjpeg video stream, rate / scale fps
+	put("LIST", uint32(116), "strl", "strh", uint32(56), "vids", "MJPG",
+		uint32(0), uint16(0), uint16(0), uint32(0), uint32(1000),
+		uint32(math.Floor(a.fps*1000+0.5)), uint32(0), frames, size,
+		^uint32(0), uint32(0), [4]uint16{0, 0, uint16(a.width), uint16(a.height)})
+	// strf: the BITMAPINFOHEADER
+	put("strf", uint32(40), uint32(40), int32(a.width), int32(a.height),
+		uint16(1), uint16(24), "MJPG", uint32(a.width*a.height*3),
+		[4]uint32{})
+	put("LIST", moviSz, "movi")
+
+	return buf.Bytes()
+}
@@ -0,0 +1,230 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+/*
+Package bitmap Go native bitmap, the pure Go counterpart of MMBitmap.
+
+Pixels are stored like MMBitmap does: Quad I (origin in the top left),
+BGR byte order, 24 or 32 bits per pixel, rows aligned to Bytewidth.
+*/
+package bitmap
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+)
+
+// Bitmap is the Go owned bitmap struct, the same layout as C.MMBitmap
+type Bitmap struct {
+	// ImageBuffer the pixels in BGR(X) order, len is Height * Bytewidth
+	ImageBuffer   []uint8
+	Width         int
+	Height        int
+	Bytewidth     int
+	BitsPerPixel  uint8
+	BytesPerPixel uint8
+}
+
+// New create a new blank 32 bits per pixel bitmap
+func New(w, h int) *Bitmap {
+	if w < 0 {
+		w = 0
+	}
+	if h < 0 {
+		h = 0
+	}
+
+	return &Bitmap{
+		ImageBuffer:   make([]uint8, w*h*4),
+		Width:         w,
+		Height:        h,
+		Bytewidth:     w * 4,
+		BitsPerPixel:  32,
+		BytesPerPixel: 4,
+	}
+}
+
+// FromBuffer copy a MMBitmap style buffer to a new bitmap,
+// bitsPerPixel should be 24 or 32, the rows may be padded to bytewidth
+func FromBuffer(buf []uint8, w, h, bytewidth int, bitsPerPixel uint8) *Bitmap {
+	bpp := int(bitsPerPixel) / 8
+	if w <= 0 || h <= 0 || bpp < 3 || bytewidth < w*bpp ||
+		len(buf) < (h-1)*bytewidth+w*bpp {
+		return nil
+	}
+
+	imgBuf := make([]uint8, h*bytewidth)
+	copy(imgBuf, buf)
+
+	return &Bitmap{
+		ImageBuffer:   imgBuf,
+		Width:         w,
+		Height:        h,
+		Bytewidth:     bytewidth,
+		BitsPerPixel:  bitsPerPixel,
+		BytesPerPixel: uint8(bpp),
+	}
+}
+
+// FromImage convert the image.Image to bitmap,
+// return the same bitmap if img is already a *Bitmap
+func FromImage(img image.Image) *Bitmap {
+	if bit, ok := img.(*Bitmap); ok {
+		return bit
+	}
+	if img == nil {
+		return nil
+	}
+
+	r := img.Bounds()
+	bit := New(r.Dx(), r.Dy())
+
+	if rgba, ok := img.(*image.RGBA); ok {
+		for y := 0; y < bit.Height; y++ {
+			src := rgba.Pix[(y+r.Min.Y-rgba.Rect.Min.Y)*rgba.Stride+
+				(r.Min.X-rgba.Rect.Min.X)*4:]
+			dst := bit.ImageBuffer[y*bit.Bytewidth:]
+			for x := 0; x < bit.Width; x++ {
+				dst[x*4] = src[x*4+2]
+				dst[x*4+1] = src[x*4+1]
+				dst[x*4+2] = src[x*4]
+				dst[x*4+3] = 0xff
+			}
+		}
+
+		return bit
+	}
+
+	for y := 0; y < bit.Height; y++ {
+		for x := 0; x < bit.Width; x++ {
+			c := color.NRGBAModel.Convert(img.At(x+r.Min.X, y+r.Min.Y)).(color.NRGBA)
+			bit.SetRGB(x, y, c.R, c.G, c.B)
+		}
+	}
+
+	return bit
+}
+
+// Equal whether the bitmaps have the same size and pixels,
+// compare the rows first, the pixels only if the rows differ
+func Equal(a, b *Bitmap) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.Width != b.Width || a.Height != b.Height {
+		return false
+	}
+
+	var (
+		n    = a.Width * int(a.BytesPerPixel)
+		same = a.BytesPerPixel == b.BytesPerPixel
+	)
+	for y := 0; y < a.Height; y++ {
+		if same && bytes.Equal(a.ImageBuffer[y*a.Bytewidth:y*a.Bytewidth+n],
+			b.ImageBuffer[y*b.Bytewidth:y*b.Bytewidth+n]) {
+			continue
+		}
+
+		for x := 0; x < a.Width; x++ {
+			if a.HexAt(x, y) != b.HexAt(x, y) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// ColorModel returns the bitmap color model, implement image.Image
+func (bit *Bitmap) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+// Bounds returns the bitmap bounds, implement image.Image
+func (bit *Bitmap) Bounds() image.Rectangle {
+	return image.Rect(0, 0, bit.Width, bit.Height)
+}
+
+// At returns the color of the pixel at (x, y), implement image.Image
+func (bit *Bitmap) At(x, y int) color.Color {
+	if !bit.InBounds(x, y) {
+		return color.RGBA{}
+	}
+
+	r, g, b := bit.RGBAt(x, y)
+	return color.RGBA{r, g, b, 0xff}
+}
+
+// InBounds bitmap point in bounds
+func (bit *Bitmap) InBounds(x, y int) bool {
+	return x >= 0 && y >= 0 && x < bit.Width && y < bit.Height
+}
+
+// RGBAt get the red, green and blue value of the pixel at (x, y),
+// no bounds checking is performed
+func (bit *Bitmap) RGBAt(x, y int) (r, g, b uint8) {
+	i := y*bit.Bytewidth + x*int(bit.BytesPerPixel)
+	return bit.ImageBuffer[i+2], bit.ImageBuffer[i+1], bit.ImageBuffer[i]
+}
+
+// HexAt get the 0xRRGGBB color of the pixel at (x, y),
+// no bounds checking is performed
+func (bit *Bitmap) HexAt(x, y int) uint32 {
+	i := y*bit.Bytewidth + x*int(bit.BytesPerPixel)
+	return uint32(bit.ImageBuffer[i+2])<<16 |
+		uint32(bit.ImageBuffer[i+1])<<8 | uint32(bit.ImageBuffer[i])
+}
+
+// SetRGB set the color of the pixel at (x, y),
+// no bounds checking is performed
+func (bit *Bitmap) SetRGB(x, y int, r, g, b uint8) {
+	i := y*bit.Bytewidth + x*int(bit.BytesPerPixel)
+	bit.ImageBuffer[i] = b
+	bit.ImageBuffer[i+1] = g
+	bit.ImageBuffer[i+2] = r
+	if bit.BytesPerPixel > 3 {
+		bit.ImageBuffer[i+3] = 0xff
+	}
+}
+
+// Copy deep copy the bitmap
+func (bit *Bitmap) Copy() *Bitmap {
+	c := *bit
+	c.ImageBuffer = make([]uint8, len(bit.ImageBuffer))
+	copy(c.ImageBuffer, bit.ImageBuffer)
+
+	return &c
+}
+
+// ToRGBA convert the bitmap to *image.RGBA
+func (bit *Bitmap) ToRGBA() *image.RGBA {
+	return rgbaRect(bit, bit.Bounds())
+}
+
+// rgbaRect returns the rect of the bitmap as *image.RGBA,
+// in the bitmap coordinates
+func rgbaRect(bit *Bitmap, r image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(r)
+	bpp := int(bit.BytesPerPixel)
+
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		src := bit.ImageBuffer[y*bit.Bytewidth+r.Min.X*bpp:]
+		dst := img.Pix[img.PixOffset(r.Min.X, y):]
+		for x := 0; x < r.Dx(); x++ {
+			dst[x*4] = src[x*bpp+2]
+			dst[x*4+1] = src[x*bpp+1]
+			dst[x*4+2] = src[x*bpp]
+			dst[x*4+3] = 0xff
+		}
+	}
+
+	return img
+}
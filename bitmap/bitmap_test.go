@@ -0,0 +1,98 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFromBufferPadded24(t *testing.T) {
+	// 2x2, 24 bits per pixel, rows padded to 8 bytes.
+	buf := []uint8{
+		1, 2, 3, 4, 5, 6, 0xee, 0xee,
+		7, 8, 9, 10, 11, 12, 0xee, 0xee,
+	}
+
+	bit := FromBuffer(buf, 2, 2, 8, 24)
+	if bit == nil {
+		t.Fatal("FromBuffer returned nil")
+	}
+	buf[0] = 0
+	if r, g, b := bit.RGBAt(0, 0); r != 3 || g != 2 || b != 1 {
+		t.Errorf("want 3 2 1, got %d %d %d", r, g, b)
+	}
+	if h := bit.HexAt(1, 1); h != 0x0c0b0a {
+		t.Errorf("want 0c0b0a, got %06x", h)
+	}
+
+	img := bit.ToRGBA()
+	if c := img.RGBAAt(1, 0); c != (color.RGBA{6, 5, 4, 0xff}) {
+		t.Errorf("ToRGBA got %v", c)
+	}
+}
+
+func TestFromBuffer32(t *testing.T) {
+	buf := []uint8{1, 2, 3, 0, 4, 5, 6, 0}
+
+	bit := FromBuffer(buf, 2, 1, 8, 32)
+	if bit == nil || bit.BytesPerPixel != 4 {
+		t.Fatal("FromBuffer 32 bits failed")
+	}
+	if c := bit.At(1, 0); c != (color.RGBA{6, 5, 4, 0xff}) {
+		t.Errorf("At got %v", c)
+	}
+
+	if FromBuffer(buf, 3, 1, 8, 32) != nil {
+		t.Error("FromBuffer should reject short bytewidth")
+	}
+}
+
+func TestFromImage(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(10, 10, 13, 12))
+	src.Set(11, 11, color.NRGBA{200, 100, 50, 0xff})
+
+	bit := FromImage(src)
+	if bit.Width != 3 || bit.Height != 2 {
+		t.Fatalf("bad size %dx%d", bit.Width, bit.Height)
+	}
+	if h := bit.HexAt(1, 1); h != 0xc86432 {
+		t.Errorf("want c86432, got %06x", h)
+	}
+	if FromImage(bit) != bit {
+		t.Error("FromImage should return the same *Bitmap")
+	}
+
+	back := FromImage(bit.ToRGBA())
+	if back.HexAt(1, 1) != 0xc86432 {
+		t.Error("RGBA round trip failed")
+	}
+}
+
+func TestEqual(t *testing.T) {
+	a := FromBuffer([]uint8{1, 2, 3, 4, 5, 6, 0xee, 0xee}, 2, 1, 8, 24)
+	b := New(2, 1)
+	b.SetRGB(0, 0, 3, 2, 1)
+	b.SetRGB(1, 0, 6, 5, 4)
+
+	if !Equal(a, b) || !Equal(b, a) || !Equal(b, FromImage(b.ToRGBA())) {
+		t.Error("the same pixels should be equal")
+	}
+
+	b.SetRGB(1, 0, 6, 5, 5)
+	if Equal(a, b) || Equal(a, New(1, 2)) || Equal(a, nil) {
+		t.Error("the different bitmaps should not be equal")
+	}
+	if !Equal(nil, nil) {
+		t.Error("nil should equal nil")
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+)
+
+// ColorOptions is the color search options
+type ColorOptions struct {
+	// Tolerance 0.0 - 1.0, 0 is the exact color and 1 is any color,
+	// as MMRGBHexSimilarToColor; in the units of the Metric
+	Tolerance float64
+	// Metric the color distance metric, default MetricRGB
+	Metric Metric
+	// HSV the MetricHSV ranges
+	HSV HSVRange
+	// Rect the search rect in the image, the zero Rect is the whole image
+	Rect image.Rectangle
+
+	// Diagonal the diagonal pixels are connected in the blobs
+	// (8-connectivity), default 4-connectivity
+	Diagonal bool
+	// MinCount the min pixel count of the blobs, the smaller are dropped
+	MinCount int
+}
+
+// Blob is a connected component of the matched color pixels
+type Blob struct {
+	// Rect the bounding box
+	Rect image.Rectangle
+	// Count the count of the pixels
+	Count int
+	// CX, CY the centroid
+	CX, CY float64
+}
+
+// Center returns the centroid rounded to a pixel
+func (b Blob) Center() image.Point {
+	return image.Pt(int(b.CX+0.5), int(b.CY+0.5))
+}
+
+// Similar whether the two hex colors (0xRRGGBB) are similar
+// within the tolerance, as MMRGBHexSimilarToColor
+func Similar(a, b uint32, tolerance float64) bool {
+	if tolerance <= 0 {
+		return a == b
+	}
+
+	dr := int(a>>16&0xff) - int(b>>16&0xff)
+	dg := int(a>>8&0xff) - int(b>>8&0xff)
+	db := int(a&0xff) - int(b&0xff)
+	d := tolerance * maxDistance
+
+	return float64(dr*dr+dg*dg+db*db) <= d*d
+}
+
+// matcher returns the compare func of the color with the metric
+func (opt *ColorOptions) matcher(color uint32) func(c uint32) bool {
+	cmp := newComparer(opt.Metric, opt.Tolerance, opt.HSV)
+	if cmp == nil {
+		return func(c uint32) bool {
+			return Similar(c, color, opt.Tolerance)
+		}
+	}
+
+	target := cmp.conv(uint8(color>>16), uint8(color>>8), uint8(color))
+	return func(c uint32) bool {
+		return cmp.within(cmp.conv(uint8(c>>16), uint8(c>>8), uint8(c)), target)
+	}
+}
+
+// colorMap returns the matched pixels of the search rect,
+// in rows order, and the rect in the bitmap coordinates
+func colorMap(img image.Image, color uint32,
+	opt *ColorOptions) ([]bool, image.Rectangle) {
+	if opt == nil {
+		opt = &ColorOptions{}
+	}
+
+	bit := FromImage(img)
+	if bit == nil {
+		return nil, image.Rectangle{}
+	}
+
+	area := bit.Bounds()
+	if !opt.Rect.Empty() {
+		area = area.Intersect(opt.Rect.Sub(img.Bounds().Min))
+	}
+
+	similar := opt.matcher(color)
+	w := area.Dx()
+	hits := make([]bool, w*area.Dy())
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			if similar(bit.HexAt(x, y)) {
+				hits[(y-area.Min.Y)*w+x-area.Min.X] = true
+			}
+		}
+	}
+
+	return hits, area
+}
+
+// FindColor find the first pixel of the color in rows order,
+// as findColorInRect
+func FindColor(img image.Image, color uint32, opt *ColorOptions) (image.Point, bool) {
+	bit := FromImage(img)
+	if bit == nil {
+		return image.Point{}, false
+	}
+	if opt == nil {
+		opt = &ColorOptions{}
+	}
+
+	origin := img.Bounds().Min
+	area := bit.Bounds()
+	if !opt.Rect.Empty() {
+		area = area.Intersect(opt.Rect.Sub(origin))
+	}
+
+	similar := opt.matcher(color)
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			if similar(bit.HexAt(x, y)) {
+				return image.Pt(x, y).Add(origin), true
+			}
+		}
+	}
+
+	return image.Point{}, false
+}
+
+// FindAllColor returns every pixel of the color in rows order,
+// as findAllColorInRect
+func FindAllColor(img image.Image, color uint32, opt *ColorOptions) []image.Point {
+	hits, area := colorMap(img, color, opt)
+	if hits == nil {
+		return nil
+	}
+
+	var (
+		points []image.Point
+		origin = img.Bounds().Min.Add(area.Min)
+		w      = area.Dx()
+	)
+	for i, v := range hits {
+		if v {
+			points = append(points, image.Pt(i%w, i/w).Add(origin))
+		}
+	}
+
+	return points
+}
+
+// CountColor returns the count of the pixels of the color,
+// as countOfColorsInRect
+func CountColor(img image.Image, color uint32, opt *ColorOptions) int {
+	hits, _ := colorMap(img, color, opt)
+
+	n := 0
+	for _, v := range hits {
+		if v {
+			n++
+		}
+	}
+
+	return n
+}
+
+// FindBlobs group the pixels of the color into the connected blobs,
+// in rows order of their first pixel
+func FindBlobs(img image.Image, color uint32, opt *ColorOptions) []Blob {
+	hits, area := colorMap(img, color, opt)
+	if hits == nil {
+		return nil
+	}
+	if opt == nil {
+		opt = &ColorOptions{}
+	}
+
+	origin := img.Bounds().Min.Add(area.Min)
+	blobs := components(hits, area.Dx(), area.Dy(), opt.Diagonal)
+
+	n := 0
+	for _, b := range blobs {
+		if b.Count < opt.MinCount {
+			continue
+		}
+
+		b.Rect = b.Rect.Add(origin)
+		b.CX += float64(origin.X)
+		b.CY += float64(origin.Y)
+		blobs[n] = b
+		n++
+	}
+
+	return blobs[:n]
+}
+
+// components returns the connected components of the hits (w x h,
+// in rows order), in rows order of their first pixel; the hits are cleared
+func components(hits []bool, w, h int, diagonal bool) []Blob {
+	var (
+		blobs []Blob
+		stack []int
+	)
+
+	dirs := []image.Point{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	if diagonal {
+		dirs = append(dirs, image.Pt(1, 1), image.Pt(-1, 1),
+			image.Pt(1, -1), image.Pt(-1, -1))
+	}
+
+	for i, v := range hits {
+		if !v {
+			continue
+		}
+
+		// flood fill, the visited pixels are cleared
+		var (
+			b      = Blob{Rect: image.Rect(i%w, i/w, i%w+1, i/w+1)}
+			sx, sy int
+		)
+		hits[i] = false
+		stack = append(stack[:0], i)
+
+		for len(stack) > 0 {
+			j := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			p := image.Pt(j%w, j/w)
+			b.Count++
+			sx += p.X
+			sy += p.Y
+			b.Rect = b.Rect.Union(image.Rect(p.X, p.Y, p.X+1, p.Y+1))
+
+			for _, d := range dirs {
+				q := p.Add(d)
+				if q.X < 0 || q.Y < 0 || q.X >= w || q.Y >= h {
+					continue
+				}
+				if k := q.Y*w + q.X; hits[k] {
+					hits[k] = false
+					stack = append(stack, k)
+				}
+			}
+		}
+
+		b.CX = float64(sx) / float64(b.Count)
+		b.CY = float64(sy) / float64(b.Count)
+		blobs = append(blobs, b)
+	}
+
+	return blobs
+}
@@ -0,0 +1,113 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+	"testing"
+)
+
+// fill set the rect of the bitmap to the hex color
+func fill(bit *Bitmap, r image.Rectangle, color uint32) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			bit.SetRGB(x, y, uint8(color>>16), uint8(color>>8), uint8(color))
+		}
+	}
+}
+
+func TestSimilar(t *testing.T) {
+	if !Similar(0x102030, 0x102030, 0) || Similar(0x102030, 0x102031, 0) {
+		t.Error("exact compare")
+	}
+	// distance 10
+	if !Similar(0x000000, 0x060800, 10/maxDistance) ||
+		Similar(0x000000, 0x060800, 9.9/maxDistance) {
+		t.Error("tolerance compare")
+	}
+}
+
+func TestFindColor(t *testing.T) {
+	bit := New(20, 10)
+	fill(bit, bit.Bounds(), 0xffffff)
+	fill(bit, image.Rect(3, 2, 5, 3), 0x336699)
+	bit.SetRGB(12, 7, 0x34, 0x66, 0x99)
+
+	p, ok := FindColor(bit, 0x336699, nil)
+	if !ok || p != image.Pt(3, 2) {
+		t.Errorf("FindColor got %v %v", p, ok)
+	}
+	p, ok = FindColor(bit, 0x336699, &ColorOptions{Rect: image.Rect(5, 0, 20, 10)})
+	if ok {
+		t.Errorf("FindColor in rect got %v", p)
+	}
+
+	all := FindAllColor(bit, 0x336699, &ColorOptions{Tolerance: 0.01})
+	want := []image.Point{{3, 2}, {4, 2}, {12, 7}}
+	if len(all) != len(want) {
+		t.Fatalf("FindAllColor got %v", all)
+	}
+	for i := range want {
+		if all[i] != want[i] {
+			t.Errorf("FindAllColor got %v, want %v", all, want)
+		}
+	}
+
+	if n := CountColor(bit, 0x336699, nil); n != 2 {
+		t.Errorf("CountColor got %d", n)
+	}
+	if n := CountColor(bit, 0x336699, &ColorOptions{Tolerance: 0.01,
+		Rect: image.Rect(4, 0, 20, 10)}); n != 2 {
+		t.Errorf("CountColor in rect got %d", n)
+	}
+
+	// the points are in the image coordinates
+	sub := bit.ToRGBA().SubImage(image.Rect(10, 5, 20, 10))
+	if all := FindAllColor(sub, 0x346699, nil); len(all) != 1 ||
+		all[0] != image.Pt(12, 7) {
+		t.Errorf("FindAllColor of sub image got %v", all)
+	}
+}
+
+func TestFindBlobs(t *testing.T) {
+	bit := New(40, 30)
+	fill(bit, bit.Bounds(), 0xffffff)
+	fill(bit, image.Rect(2, 2, 12, 6), 0xff0000)
+	fill(bit, image.Rect(20, 10, 24, 14), 0xff0000)
+	// diagonal neighbor of the second blob
+	bit.SetRGB(24, 14, 0xff, 0, 0)
+	bit.SetRGB(35, 25, 0xff, 0, 0)
+
+	blobs := FindBlobs(bit, 0xff0000, nil)
+	if len(blobs) != 4 {
+		t.Fatalf("FindBlobs got %+v", blobs)
+	}
+
+	b := blobs[0]
+	if b.Rect != image.Rect(2, 2, 12, 6) || b.Count != 40 ||
+		b.CX != 6.5 || b.CY != 3.5 || b.Center() != image.Pt(7, 4) {
+		t.Errorf("blob 0 got %+v", b)
+	}
+	if blobs[1].Count != 16 || blobs[2].Rect != image.Rect(24, 14, 25, 15) {
+		t.Errorf("blobs got %+v", blobs)
+	}
+
+	blobs = FindBlobs(bit, 0xff0000, &ColorOptions{Diagonal: true, MinCount: 2})
+	if len(blobs) != 2 || blobs[1].Count != 17 ||
+		blobs[1].Rect != image.Rect(20, 10, 25, 15) {
+		t.Errorf("8-connected blobs got %+v", blobs)
+	}
+
+	blobs = FindBlobs(bit, 0xff0000, &ColorOptions{Rect: image.Rect(22, 0, 40, 30)})
+	if len(blobs) != 3 || blobs[0].Rect != image.Rect(22, 10, 24, 14) {
+		t.Errorf("blobs in rect got %+v", blobs)
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"errors"
+	"image"
+	"sort"
+)
+
+// ErrSizeMismatch the compared images are not the same size
+var ErrSizeMismatch = errors.New("Images are not the same size")
+
+// DiffOptions is the bitmap diff options
+type DiffOptions struct {
+	// Tolerance, Metric and HSV compare the pixels, see ColorOptions
+	Tolerance float64
+	Metric    Metric
+	HSV       HSVRange
+	// Rect the compared rect, the zero Rect is the whole image
+	Rect image.Rectangle
+
+	// Gap the changed rects within Gap pixels are merged,
+	// 0 merges the touching and overlapping rects
+	Gap int
+	// MinCount the min changed pixels of a rect, the smaller
+	// are dropped (e.g. the noise)
+	MinCount int
+}
+
+// changed returns the changed pixels of the compared rect,
+// in rows order, and the rect in the a coordinates
+func (opt *DiffOptions) changed(a, b *Bitmap) ([]bool, image.Rectangle) {
+	area := a.Bounds()
+	if !opt.Rect.Empty() {
+		area = area.Intersect(opt.Rect)
+	}
+
+	same := func(c1, c2 uint32) bool {
+		return Similar(c1, c2, opt.Tolerance)
+	}
+	if cmp := newComparer(opt.Metric, opt.Tolerance, opt.HSV); cmp != nil {
+		same = func(c1, c2 uint32) bool {
+			return cmp.within(cmp.conv(uint8(c1>>16), uint8(c1>>8), uint8(c1)),
+				cmp.conv(uint8(c2>>16), uint8(c2>>8), uint8(c2)))
+		}
+	}
+
+	w := area.Dx()
+	hits := make([]bool, w*area.Dy())
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			c1, c2 := a.HexAt(x, y), b.HexAt(x, y)
+			if c1 != c2 && !same(c1, c2) {
+				hits[(y-area.Min.Y)*w+x-area.Min.X] = true
+			}
+		}
+	}
+
+	return hits, area
+}
+
+// nearRects whether the rects are within gap pixels
+func nearRects(r, o image.Rectangle, gap int) bool {
+	dx := o.Min.X - r.Max.X
+	if d := r.Min.X - o.Max.X; d > dx {
+		dx = d
+	}
+	dy := o.Min.Y - r.Max.Y
+	if d := r.Min.Y - o.Max.Y; d > dy {
+		dy = d
+	}
+
+	return dx <= gap && dy <= gap
+}
+
+// mergeCell is the grid cell size of mergeNear
+const mergeCell = 32
+
+// mergeRects merge the blobs within gap pixels, the counts are summed;
+// a merged rect may be near the others, it is merged again
+func mergeRects(blobs []Blob, gap int) []Blob {
+	for {
+		merged := mergeNear(blobs, gap)
+		if len(merged) == len(blobs) {
+			return merged
+		}
+		blobs = merged
+	}
+}
+
+// mergeNear merge the groups of the near blobs, the union-find
+// of the blobs in the same grid cells, in the blobs order
+func mergeNear(blobs []Blob, gap int) []Blob {
+	parent := make([]int, len(blobs))
+	for i := range parent {
+		parent[i] = i
+	}
+	find := func(i int) int {
+		for parent[i] != i {
+			parent[i] = parent[parent[i]]
+			i = parent[i]
+		}
+		return i
+	}
+
+	// the near rects share a cell of the rects grown by gap + 1
+	cells := make(map[image.Point][]int)
+	for i, b := range blobs {
+		r := b.Rect.Inset(-gap - 1)
+		for cy := r.Min.Y / mergeCell; cy <= (r.Max.Y-1)/mergeCell; cy++ {
+			for cx := r.Min.X / mergeCell; cx <= (r.Max.X-1)/mergeCell; cx++ {
+				c := image.Pt(cx, cy)
+				for _, j := range cells[c] {
+					if ri, rj := find(i), find(j); ri != rj &&
+						nearRects(b.Rect, blobs[j].Rect, gap) {
+						parent[ri] = rj
+					}
+				}
+				cells[c] = append(cells[c], i)
+			}
+		}
+	}
+
+	var (
+		res   []Blob
+		index = make(map[int]int)
+	)
+	for i, b := range blobs {
+		root := find(i)
+		k, ok := index[root]
+		if !ok {
+			index[root] = len(res)
+			res = append(res, b)
+			continue
+		}
+
+		res[k].Rect = res[k].Rect.Union(b.Rect)
+		res[k].Count += b.Count
+	}
+
+	return res
+}
+
+// Diff compare the two images of the same size, return the changed
+// rects in the a coordinates, in rows order; nil if nothing changed
+func Diff(a, b image.Image, opt *DiffOptions) ([]image.Rectangle, error) {
+	_, rects, err := diff(a, b, opt, false)
+	return rects, err
+}
+
+// DiffImage compare as Diff and returns the diff visualization too:
+// b in the faded gray, the changed pixels in red and the changed rects
+// in green
+func DiffImage(a, b image.Image, opt *DiffOptions) (*Bitmap, []image.Rectangle, error) {
+	return diff(a, b, opt, true)
+}
+
+func diff(a, b image.Image, opt *DiffOptions, visual bool) (*Bitmap,
+	[]image.Rectangle, error) {
+	if opt == nil {
+		opt = &DiffOptions{}
+	}
+
+	abit, bbit := FromImage(a), FromImage(b)
+	if abit == nil || bbit == nil || abit.Width != bbit.Width ||
+		abit.Height != bbit.Height {
+		return nil, nil, ErrSizeMismatch
+	}
+
+	// the rect is in the a coordinates
+	o := *opt
+	o.Rect = opt.Rect.Sub(a.Bounds().Min)
+	hits, area := o.changed(abit, bbit)
+
+	var vis *Bitmap
+	if visual {
+		vis = fade(bbit)
+		for i, v := range hits {
+			if v {
+				vis.SetRGB(area.Min.X+i%area.Dx(), area.Min.Y+i/area.Dx(), 0xff, 0, 0)
+			}
+		}
+	}
+
+	blobs := mergeRects(components(hits, area.Dx(), area.Dy(), true), o.Gap)
+
+	var rects []image.Rectangle
+	for _, bl := range blobs {
+		if bl.Count < o.MinCount {
+			continue
+		}
+
+		r := bl.Rect.Add(area.Min)
+		if vis != nil {
+			outline(vis, r, 0, 0xff, 0)
+		}
+		rects = append(rects, r.Add(a.Bounds().Min))
+	}
+
+	sort.Slice(rects, func(i, j int) bool {
+		if rects[i].Min.Y != rects[j].Min.Y {
+			return rects[i].Min.Y < rects[j].Min.Y
+		}
+		return rects[i].Min.X < rects[j].Min.X
+	})
+
+	return vis, rects, nil
+}
+
+// fade returns the gray of the bitmap in 1/3 brightness
+func fade(bit *Bitmap) *Bitmap {
+	return mapGray(bit, func(y uint8) uint8 { return y / 3 })
+}
+
+// outline draw the rect border
+func outline(bit *Bitmap, r image.Rectangle, red, green, blue uint8) {
+	for x := r.Min.X; x < r.Max.X; x++ {
+		bit.SetRGB(x, r.Min.Y, red, green, blue)
+		bit.SetRGB(x, r.Max.Y-1, red, green, blue)
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		bit.SetRGB(r.Min.X, y, red, green, blue)
+		bit.SetRGB(r.Max.X-1, y, red, green, blue)
+	}
+}
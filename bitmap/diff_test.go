@@ -0,0 +1,163 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	a := noise(60, 40, 12)
+	b := a.Copy()
+
+	rects, err := Diff(a, b, nil)
+	if err != nil || rects != nil {
+		t.Fatalf("the same images got %v %v", rects, err)
+	}
+
+	fill(b, image.Rect(5, 5, 10, 8), 0xff00ff)
+	fill(b, image.Rect(12, 5, 14, 9), 0xff00ff)
+	fill(b, image.Rect(40, 30, 45, 35), 0x00ff00)
+	b.SetRGB(50, 2, 0, 0, 0)
+
+	for _, c := range []struct {
+		opt  DiffOptions
+		want []image.Rectangle
+	}{
+		{DiffOptions{}, []image.Rectangle{
+			image.Rect(50, 2, 51, 3), image.Rect(5, 5, 10, 8),
+			image.Rect(12, 5, 14, 9), image.Rect(40, 30, 45, 35),
+		}},
+		{DiffOptions{Gap: 2, MinCount: 2}, []image.Rectangle{
+			image.Rect(5, 5, 14, 9), image.Rect(40, 30, 45, 35),
+		}},
+		{DiffOptions{Rect: image.Rect(0, 0, 30, 20)}, []image.Rectangle{
+			image.Rect(5, 5, 10, 8), image.Rect(12, 5, 14, 9),
+		}},
+	} {
+		rects, err := Diff(a, b, &c.opt)
+		if err != nil || !reflect.DeepEqual(rects, c.want) {
+			t.Errorf("%+v: got %v %v, want %v", c.opt, rects, err, c.want)
+		}
+	}
+
+	// the small change is in the tolerance
+	c := a.Copy()
+	r, g, bl := c.RGBAt(20, 20)
+	c.SetRGB(20, 20, r^2, g, bl)
+	if rects, _ := Diff(a, c, &DiffOptions{Tolerance: 0.01}); rects != nil {
+		t.Errorf("tolerance got %v", rects)
+	}
+	if rects, _ := Diff(a, c, &DiffOptions{Metric: MetricChannel,
+		Tolerance: 1}); len(rects) != 1 {
+		t.Errorf("channel metric got %v", rects)
+	}
+
+	if _, err := Diff(a, New(60, 41), nil); err != ErrSizeMismatch {
+		t.Errorf("size mismatch got %v", err)
+	}
+
+	// the images in the other coordinates
+	sa := a.ToRGBA().SubImage(image.Rect(30, 20, 60, 40))
+	sb := b.ToRGBA().SubImage(image.Rect(30, 20, 60, 40))
+	rects, _ = Diff(sa, b.ToRGBA().SubImage(image.Rect(30, 20, 60, 40)), nil)
+	if len(rects) != 1 || rects[0] != image.Rect(40, 30, 45, 35) {
+		t.Errorf("sub images got %v", rects)
+	}
+
+	vis, rects, err := DiffImage(sa, sb, &DiffOptions{
+		Rect: image.Rect(40, 30, 60, 40),
+	})
+	if err != nil || len(rects) != 1 || vis.Width != 30 {
+		t.Fatalf("DiffImage got %v %v", rects, err)
+	}
+	if vis.HexAt(10, 10) != 0x00ff00 || vis.HexAt(12, 12) != 0xff0000 {
+		t.Errorf("DiffImage rect %06x, changed %06x", vis.HexAt(10, 10),
+			vis.HexAt(12, 12))
+	}
+	if r, g, b := vis.RGBAt(0, 0); r != g || g != b || r > 85 {
+		t.Errorf("DiffImage faded got %d %d %d", r, g, b)
+	}
+}
+
+// mergeNaive is the pairwise merge until no rects are near
+func mergeNaive(blobs []Blob, gap int) []Blob {
+	for merged := true; merged; {
+		merged = false
+		for i := 0; i < len(blobs); i++ {
+			for j := i + 1; j < len(blobs); j++ {
+				if nearRects(blobs[i].Rect, blobs[j].Rect, gap) {
+					blobs[i].Rect = blobs[i].Rect.Union(blobs[j].Rect)
+					blobs[i].Count += blobs[j].Count
+					blobs = append(blobs[:j], blobs[j+1:]...)
+					merged, j = true, i
+				}
+			}
+		}
+	}
+
+	return blobs
+}
+
+func TestMergeRects(t *testing.T) {
+	sorted := func(blobs []Blob) []Blob {
+		sort.Slice(blobs, func(i, j int) bool {
+			a, b := blobs[i].Rect, blobs[j].Rect
+			if a.Min.Y != b.Min.Y {
+				return a.Min.Y < b.Min.Y
+			}
+			return a.Min.X < b.Min.X
+		})
+		return blobs
+	}
+
+	// a and b touch, their union overlaps c
+	chain := []Blob{
+		{Rect: image.Rect(0, 0, 10, 2), Count: 1},
+		{Rect: image.Rect(0, 19, 2, 21), Count: 1},
+		{Rect: image.Rect(10, 0, 12, 20), Count: 1},
+		{Rect: image.Rect(40, 38, 41, 39), Count: 1},
+	}
+	got := mergeRects(append([]Blob(nil), chain...), 0)
+	if len(got) != 2 || got[0].Rect != image.Rect(0, 0, 12, 21) || got[0].Count != 3 {
+		t.Errorf("chain got %+v", got)
+	}
+
+	r := rand.New(rand.NewSource(13))
+	for _, gap := range []int{0, 3, 40} {
+		var blobs []Blob
+		for i := 0; i < 300; i++ {
+			x, y := r.Intn(600), r.Intn(400)
+			blobs = append(blobs, Blob{Rect: image.Rect(x, y, x+1+r.Intn(6),
+				y+1+r.Intn(6)), Count: 1})
+		}
+
+		want := sorted(mergeNaive(append([]Blob(nil), blobs...), gap))
+		got := sorted(mergeRects(blobs, gap))
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("gap %d: want %d rects, got %d", gap, len(want), len(got))
+		}
+	}
+}
+
+func BenchmarkDiffNoise(b *testing.B) {
+	a, c := noise(1920, 1080, 14), noise(1920, 1080, 15)
+	opt := &DiffOptions{Tolerance: 0.3}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		Diff(a, c, opt)
+	}
+}
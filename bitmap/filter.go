@@ -0,0 +1,174 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+	"math"
+)
+
+// defaultThreshold is the binary threshold if Options.Threshold is nil
+const defaultThreshold = 128
+
+// Filter is the preprocessing of the haystack and the needle,
+// the filtered images are gray (r = g = b)
+type Filter int
+
+const (
+	// FilterNone compares the colors
+	FilterNone Filter = iota
+	// FilterGray compares the luminance
+	FilterGray
+	// FilterThreshold compares the binary images, the pixels with
+	// the luminance >= Options.Threshold (default 128) are white, the others black
+	FilterThreshold
+	// FilterEdge compares the Sobel edge magnitude of the luminance,
+	// the edges of a shape are the same in the dark and light themes;
+	// the 1 pixel border of the needle is ignored
+	FilterEdge
+)
+
+// Preprocess returns the image filtered by opt.Filter,
+// as the search compares it, e.g. to save and check the edge map
+func Preprocess(img image.Image, opt *Options) *Bitmap {
+	bit := FromImage(img)
+	if bit == nil || opt == nil || opt.Filter == FilterNone {
+		return bit
+	}
+
+	return opt.filter(bit)
+}
+
+// filter apply the filter of the options to the bitmap
+func (opt *Options) filter(bit *Bitmap) *Bitmap {
+	switch opt.Filter {
+	case FilterGray:
+		return gray(bit)
+	case FilterThreshold:
+		level := uint8(defaultThreshold)
+		if opt.Threshold != nil {
+			level = *opt.Threshold
+		}
+		return threshold(bit, level)
+	case FilterEdge:
+		return sobel(bit)
+	}
+
+	return bit
+}
+
+// luma returns the luminance of the BGR pixel, as color.GrayModel
+func luma(px []uint8) uint8 {
+	y := (19595*uint32(px[2]) + 38470*uint32(px[1]) +
+		7471*uint32(px[0]) + 1<<15) >> 16
+
+	return uint8(y)
+}
+
+// mapGray make a gray bitmap of fn(luminance)
+func mapGray(bit *Bitmap, fn func(y uint8) uint8) *Bitmap {
+	dst := New(bit.Width, bit.Height)
+	bpp, dbpp := int(bit.BytesPerPixel), int(dst.BytesPerPixel)
+
+	for y := 0; y < bit.Height; y++ {
+		row := bit.ImageBuffer[y*bit.Bytewidth:]
+		drow := dst.ImageBuffer[y*dst.Bytewidth:]
+
+		for x := 0; x < bit.Width; x++ {
+			v := fn(luma(row[x*bpp:]))
+			i := x * dbpp
+			drow[i], drow[i+1], drow[i+2] = v, v, v
+		}
+	}
+
+	return dst
+}
+
+// gray make the grayscale bitmap
+func gray(bit *Bitmap) *Bitmap {
+	return mapGray(bit, func(y uint8) uint8 { return y })
+}
+
+// threshold make the binary bitmap, luminance >= level is white
+func threshold(bit *Bitmap, level uint8) *Bitmap {
+	return mapGray(bit, func(y uint8) uint8 {
+		if y >= level {
+			return 0xff
+		}
+		return 0
+	})
+}
+
+// sobel make the Sobel edge magnitude bitmap of the luminance,
+// the border pixels are clamped; a 255 step edge is 255
+func sobel(bit *Bitmap) *Bitmap {
+	w, h := bit.Width, bit.Height
+	lum := make([]int, w*h)
+	bpp := int(bit.BytesPerPixel)
+	for y := 0; y < h; y++ {
+		row := bit.ImageBuffer[y*bit.Bytewidth:]
+		for x := 0; x < w; x++ {
+			lum[y*w+x] = int(luma(row[x*bpp:]))
+		}
+	}
+
+	at := func(x, y int) int {
+		return lum[clamp(y, h)*w+clamp(x, w)]
+	}
+
+	dst := New(w, h)
+	dbpp := int(dst.BytesPerPixel)
+	for y := 0; y < h; y++ {
+		drow := dst.ImageBuffer[y*dst.Bytewidth:]
+		for x := 0; x < w; x++ {
+			gx := at(x+1, y-1) + 2*at(x+1, y) + at(x+1, y+1) -
+				at(x-1, y-1) - 2*at(x-1, y) - at(x-1, y+1)
+			gy := at(x-1, y+1) + 2*at(x, y+1) + at(x+1, y+1) -
+				at(x-1, y-1) - 2*at(x, y-1) - at(x+1, y-1)
+
+			m := math.Sqrt(float64(gx*gx+gy*gy)) / 4
+			if m > 255 {
+				m = 255
+			}
+
+			v := uint8(m + 0.5)
+			i := x * dbpp
+			drow[i], drow[i+1], drow[i+2] = v, v, v
+		}
+	}
+
+	return dst
+}
+
+// edgeMask ignore the 1 pixel border of the mask, the needle edges
+// there depend on the haystack pixels around the needle;
+// the mask is not changed if the needle is too small
+func edgeMask(m *Mask, w, h int) *Mask {
+	if w <= 2 || h <= 2 {
+		return m
+	}
+
+	dst := NewMask(w, h)
+	if m != nil {
+		copy(dst.Pix, m.Pix)
+	}
+
+	for x := 0; x < w; x++ {
+		dst.Set(x, 0, false)
+		dst.Set(x, h-1, false)
+	}
+	for y := 0; y < h; y++ {
+		dst.Set(0, y, false)
+		dst.Set(w-1, y, false)
+	}
+
+	return dst
+}
@@ -0,0 +1,660 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// maxDistance is the max euclidean distance between two rgb colors,
+// the same scale as MMRGBColorSimilarToColor
+const maxDistance = 442.0
+
+// defaultScaleStep is the scale step if MinScale or MaxScale is set
+const defaultScaleStep = 0.1
+
+// Method is the bitmap match method
+type Method int
+
+const (
+	// MatchTolerance every pixel must be similar within the tolerance,
+	// like MMRGBColorSimilarToColor, it is exact if the tolerance is 0
+	MatchTolerance Method = iota
+	// MatchExact every pixel must be the same color
+	MatchExact
+	// MatchSSD the sum of squared differences, the score is
+	// 1 - RMS(distance) / 442, matched if score >= 1 - tolerance
+	MatchSSD
+	// MatchNCC the zero-mean normalized cross-correlation, the score is
+	// the correlation (clamped to 0 - 1), matched if score >= 1 - tolerance;
+	// it is not affected by the brightness and contrast (gain) shifts;
+	// a flat needle on a flat window has no correlation, the score is
+	// 1 - distance(mean colors) / 442 then
+	MatchNCC
+)
+
+// eps is the float compare epsilon of the scores
+const eps = 1e-9
+
+// Options is the bitmap search options
+type Options struct {
+	// Tolerance 0.0 - 1.0, 0 is the exact color and 1 is any color;
+	// in the units of the Metric for MatchTolerance
+	Tolerance float64
+	// Method the match method, default MatchTolerance
+	Method Method
+	// Metric the color distance metric of MatchTolerance, default
+	// MetricRGB; the MatchSSD and MatchNCC scores are always RGB
+	Metric Metric
+	// HSV the MetricHSV ranges
+	HSV HSVRange
+	// Rect the search rect in the haystack, the zero Rect is the whole haystack
+	Rect image.Rectangle
+	// Max the max count of FindAll results, 0 is unlimited
+	Max int
+	// Workers the count of goroutines to search the haystack tiles,
+	// e.g. runtime.NumCPU(), the search is sequential if <= 1
+	Workers int
+	// Pyramid the levels of the coarse to fine search, 0 is disabled;
+	// the haystack and the needle are downsampled by 2^Pyramid, Find
+	// refines the best 16 candidates of the coarse level in the full
+	// resolution; FindAll also refines every coarse candidate within
+	// the tolerance (plus a 0.1 score margin); the coarse level is RGB,
+	// the pyramid is not used for MetricCIE76, MetricCIEDE2000 and
+	// MetricHSV, their tolerance has no RGB bound
+	Pyramid int
+	// Mask the needle mask, the ignored pixels are not compared;
+	// if nil or empty, the fully transparent pixels of the needle are
+	// ignored; nothing is found if len(Pix) != Width * Height or the
+	// size is not the needle size (it is not resized)
+	Mask *Mask
+	// Filter the preprocessing of the haystack and the needle, e.g.
+	// FilterEdge to match the structure instead of the colors;
+	// the tolerance is the distance of the filtered gray pixels
+	Filter Filter
+	// Threshold the FilterThreshold level, nil is 128
+	Threshold *uint8
+
+	// MinScale, MaxScale the needle scale range, e.g. 0.5 - 2.0,
+	// the needle is resampled and searched at every ScaleStep (default 0.1),
+	// only the 1.0 scale is searched if both are 0
+	MinScale  float64
+	MaxScale  float64
+	ScaleStep float64
+}
+
+// scales returns the needle scales to search
+func (opt *Options) scales() []float64 {
+	if opt.MinScale <= 0 && opt.MaxScale <= 0 {
+		return []float64{1}
+	}
+
+	min, max, step := opt.MinScale, opt.MaxScale, opt.ScaleStep
+	if min <= 0 {
+		min = 1
+	}
+	if max < min {
+		max = min
+	}
+	if step <= 0 {
+		step = defaultScaleStep
+	}
+
+	var scales []float64
+	for i := 0; ; i++ {
+		s := min + float64(i)*step
+		if s > max+step/1e3 {
+			break
+		}
+		scales = append(scales, s)
+	}
+
+	return scales
+}
+
+// Result is the bitmap search result
+type Result struct {
+	// X, Y the top left point of the needle in the haystack
+	X, Y int
+	// W, H the size of the matched needle
+	W, H int
+	// Score the match score 0.0 - 1.0, 1 is the exact match
+	Score float64
+	// Scale the needle scale of the match
+	Scale float64
+	// Ignored the count of the ignored (masked) needle pixels
+	Ignored int
+}
+
+// Point returns the top left point of the result
+func (r Result) Point() image.Point {
+	return image.Pt(r.X, r.Y)
+}
+
+// Rect returns the matched rect of the haystack
+func (r Result) Rect() image.Rectangle {
+	return image.Rect(r.X, r.Y, r.X+r.W, r.Y+r.H)
+}
+
+// overlaps whether the two results overlap
+func (r Result) overlaps(o Result) bool {
+	return r.Rect().Overlaps(o.Rect())
+}
+
+// resultGrid is the spatial index of the kept results, a cell is the
+// largest result size, so a result overlaps the neighbor cells only
+type resultGrid struct {
+	w, h  int
+	cells map[image.Point][]Result
+}
+
+// newResultGrid returns the grid of the needles sizes
+func newResultGrid(list []*searcher) *resultGrid {
+	g := &resultGrid{w: 1, h: 1, cells: make(map[image.Point][]Result)}
+	for _, s := range list {
+		if s.needle.Width > g.w {
+			g.w = s.needle.Width
+		}
+		if s.needle.Height > g.h {
+			g.h = s.needle.Height
+		}
+	}
+
+	return g
+}
+
+// cell returns the cell of the point, floor divided
+func (g *resultGrid) cell(x, y int) image.Point {
+	cx, cy := x/g.w, y/g.h
+	if x < 0 && x%g.w != 0 {
+		cx--
+	}
+	if y < 0 && y%g.h != 0 {
+		cy--
+	}
+
+	return image.Pt(cx, cy)
+}
+
+// overlaps whether the result overlaps a kept result
+func (g *resultGrid) overlaps(r Result) bool {
+	c := g.cell(r.X, r.Y)
+	for y := c.Y - 1; y <= c.Y+1; y++ {
+		for x := c.X - 1; x <= c.X+1; x++ {
+			for _, o := range g.cells[image.Pt(x, y)] {
+				if r.overlaps(o) {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// add keep the result
+func (g *resultGrid) add(r Result) {
+	c := g.cell(r.X, r.Y)
+	g.cells[c] = append(g.cells[c], r)
+}
+
+// searcher holds the prepared state of one search
+type searcher struct {
+	hay    *Bitmap
+	needle *Bitmap
+	origin image.Point
+	rect   image.Rectangle // candidate top left points, in hay coordinates
+	scale  float64
+	mask   []bool // nil compares all pixels
+	count  int    // count of the compared pixels
+
+	method Method
+	limit  float64 // squared per pixel distance limit
+	ssdMax float64 // MatchSSD sum limit
+	minNCC float64 // MatchNCC score limit
+
+	nzm   []float64  // MatchNCC zero-mean needle, 3 channels per pixel
+	nvar  float64    // MatchNCC needle variance sum
+	nmean [3]float64 // MatchNCC needle mean color, BGR
+
+	cmp   *comparer    // MatchTolerance metric, nil is MetricRGB
+	nconv [][3]float32 // the needle pixels converted by cmp
+	hconv *convMap     // the searched haystack converted by cmp
+}
+
+// best whether the search returns the best matches
+// instead of the first matches in rows order
+func (opt *Options) best() bool {
+	return opt.Method == MatchSSD || opt.Method == MatchNCC ||
+		opt.Pyramid > 0 || len(opt.scales()) > 1
+}
+
+// newSearchers prepare a searcher for every needle scale
+func newSearchers(haystack, needle image.Image, opt *Options) []*searcher {
+	if opt == nil {
+		opt = &Options{}
+	}
+
+	hay := FromImage(haystack)
+	nbit := FromImage(needle)
+	if hay == nil || nbit == nil || nbit.Width == 0 || nbit.Height == 0 {
+		return nil
+	}
+
+	origin := haystack.Bounds().Min
+	area := hay.Bounds()
+	if !opt.Rect.Empty() {
+		area = area.Intersect(opt.Rect.Sub(origin))
+	}
+
+	if opt.Filter != FilterNone {
+		// filter the searched area only, the edges need 1 more pixel
+		r := area.Inset(-1).Intersect(hay.Bounds())
+		if r.Empty() {
+			return nil
+		}
+		if r != hay.Bounds() {
+			hay = Crop(hay, r)
+			origin = origin.Add(r.Min)
+			area = area.Sub(r.Min)
+		}
+		hay = opt.filter(hay)
+	}
+
+	mask := opt.Mask
+	if mask != nil && mask.empty() {
+		mask = nil
+	}
+	if mask == nil {
+		mask = AlphaMask(needle)
+	} else if !mask.valid() || mask.Width != nbit.Width ||
+		mask.Height != nbit.Height {
+		return nil
+	}
+
+	d := opt.Tolerance * maxDistance
+
+	var (
+		list  []*searcher
+		hconv *convMap
+	)
+	for _, scale := range opt.scales() {
+		sbit, smask := nbit, mask
+		if scale != 1 {
+			w := int(math.Floor(float64(nbit.Width)*scale + 0.5))
+			h := int(math.Floor(float64(nbit.Height)*scale + 0.5))
+			if w < 1 || h < 1 || w > area.Dx() || h > area.Dy() {
+				continue
+			}
+			sbit = resample(nbit, w, h)
+			if mask != nil {
+				smask = mask.resize(w, h)
+			}
+		}
+		if opt.Filter != FilterNone {
+			sbit = opt.filter(sbit)
+		}
+		if opt.Filter == FilterEdge {
+			smask = edgeMask(smask, sbit.Width, sbit.Height)
+		}
+
+		s := &searcher{
+			hay:    hay,
+			needle: sbit,
+			origin: origin,
+			scale:  scale,
+			method: opt.Method,
+			limit:  d * d,
+			count:  sbit.Width * sbit.Height,
+		}
+		if s.method == MatchTolerance && opt.Tolerance <= 0 &&
+			opt.Metric != MetricHSV {
+			s.method = MatchExact
+		}
+
+		if smask != nil {
+			s.mask = smask.Pix
+			s.count -= smask.Ignored()
+			if s.count == 0 {
+				continue
+			}
+		}
+
+		s.ssdMax = s.limit * float64(s.count)
+		s.minNCC = 1 - opt.Tolerance - eps
+		if s.method == MatchNCC {
+			s.prepareNCC()
+		}
+		if s.method == MatchTolerance {
+			s.prepareMetric(opt)
+		}
+
+		s.rect = image.Rect(area.Min.X, area.Min.Y,
+			area.Max.X-sbit.Width+1, area.Max.Y-sbit.Height+1)
+		if s.rect.Empty() {
+			continue
+		}
+
+		if s.cmp != nil {
+			// the scales share the converted haystack
+			if hconv == nil {
+				hconv = newConvMap(hay, area, s.cmp, opt.Workers)
+			}
+			s.hconv = hconv
+		}
+
+		list = append(list, s)
+	}
+
+	return list
+}
+
+// prepareNCC compute the zero-mean needle
+func (s *searcher) prepareNCC() {
+	var mean [3]float64
+
+	s.nzm = make([]float64, s.needle.Width*s.needle.Height*3)
+	s.eachNeedle(func(i int, px []uint8) {
+		for c := 0; c < 3; c++ {
+			mean[c] += float64(px[c])
+		}
+	})
+
+	for c := 0; c < 3; c++ {
+		mean[c] /= float64(s.count)
+	}
+	s.nmean = mean
+
+	s.nvar = 0
+	s.eachNeedle(func(i int, px []uint8) {
+		for c := 0; c < 3; c++ {
+			v := float64(px[c]) - mean[c]
+			s.nzm[i*3+c] = v
+			s.nvar += v * v
+		}
+	})
+}
+
+// prepareMetric convert the needle pixels for the metric
+func (s *searcher) prepareMetric(opt *Options) {
+	s.cmp = newComparer(opt.Metric, opt.Tolerance, opt.HSV)
+	if s.cmp == nil {
+		return
+	}
+
+	s.nconv = make([][3]float32, s.needle.Width*s.needle.Height)
+	s.eachNeedle(func(i int, px []uint8) {
+		s.nconv[i] = s.cmp.conv32(px[2], px[1], px[0])
+	})
+}
+
+// eachNeedle call fn on every compared needle pixel,
+// i is the pixel index and px the BGR bytes
+func (s *searcher) eachNeedle(fn func(i int, px []uint8)) {
+	needle := s.needle
+	bpp := int(needle.BytesPerPixel)
+
+	for y := 0; y < needle.Height; y++ {
+		row := needle.ImageBuffer[y*needle.Bytewidth:]
+		for x := 0; x < needle.Width; x++ {
+			i := y*needle.Width + x
+			if s.mask != nil && !s.mask[i] {
+				continue
+			}
+			fn(i, row[x*bpp:x*bpp+3])
+		}
+	}
+}
+
+// matchNCC compute the zero-mean normalized cross-correlation at (x, y)
+func (s *searcher) matchNCC(x, y int) (float64, bool) {
+	var (
+		hay    = s.hay
+		needle = s.needle
+		hbpp   = int(hay.BytesPerPixel)
+		sh     [3]float64
+		shh    [3]float64
+		shn    float64
+	)
+
+	for ny := 0; ny < needle.Height; ny++ {
+		hrow := hay.ImageBuffer[(y+ny)*hay.Bytewidth+x*hbpp:]
+		for nx := 0; nx < needle.Width; nx++ {
+			i := ny*needle.Width + nx
+			if s.mask != nil && !s.mask[i] {
+				continue
+			}
+
+			px := hrow[nx*hbpp:]
+			for c := 0; c < 3; c++ {
+				v := float64(px[c])
+				sh[c] += v
+				shh[c] += v * v
+				shn += v * s.nzm[i*3+c]
+			}
+		}
+	}
+
+	hvar := 0.0
+	for c := 0; c < 3; c++ {
+		hvar += shh[c] - sh[c]*sh[c]/float64(s.count)
+	}
+
+	var score float64
+	switch {
+	case hvar < eps && s.nvar < eps:
+		// both flat, compare the mean colors
+		var d float64
+		for c := 0; c < 3; c++ {
+			v := sh[c]/float64(s.count) - s.nmean[c]
+			d += v * v
+		}
+		score = 1 - math.Sqrt(d)/maxDistance
+	case hvar < eps || s.nvar < eps:
+		score = 0
+	default:
+		score = shn / math.Sqrt(hvar*s.nvar)
+	}
+
+	if score < 0 {
+		score = 0
+	}
+	if score > 1 {
+		score = 1
+	}
+
+	return score, score >= s.minNCC
+}
+
+// match compare the needle with the haystack at (x, y),
+// return the score and whether it matched
+func (s *searcher) match(x, y int) (float64, bool) {
+	if s.method == MatchNCC {
+		return s.matchNCC(x, y)
+	}
+
+	var (
+		hay    = s.hay
+		needle = s.needle
+		hbpp   = int(hay.BytesPerPixel)
+		nbpp   = int(needle.BytesPerPixel)
+		sum    float64
+	)
+
+	for ny := 0; ny < needle.Height; ny++ {
+		hrow := hay.ImageBuffer[(y+ny)*hay.Bytewidth+x*hbpp:]
+		nrow := needle.ImageBuffer[ny*needle.Bytewidth:]
+
+		var crow [][3]float32
+		if s.cmp != nil {
+			r := s.hconv.rect
+			crow = s.hconv.px[(y+ny-r.Min.Y)*r.Dx()+x-r.Min.X:]
+		}
+
+		for nx := 0; nx < needle.Width; nx++ {
+			if s.mask != nil && !s.mask[ny*needle.Width+nx] {
+				continue
+			}
+
+			hi, ni := nx*hbpp, nx*nbpp
+			db := int(hrow[hi]) - int(nrow[ni])
+			dg := int(hrow[hi+1]) - int(nrow[ni+1])
+			dr := int(hrow[hi+2]) - int(nrow[ni+2])
+
+			if s.method == MatchExact {
+				if db != 0 || dg != 0 || dr != 0 {
+					return 0, false
+				}
+				continue
+			}
+
+			d := float64(dr*dr + dg*dg + db*db)
+			sum += d
+			if s.method == MatchSSD {
+				if sum > s.ssdMax {
+					return 0, false
+				}
+				continue
+			}
+
+			if s.cmp != nil {
+				if !s.cmp.within32(crow[nx], s.nconv[ny*needle.Width+nx]) {
+					return 0, false
+				}
+				continue
+			}
+
+			if d > s.limit {
+				return 0, false
+			}
+		}
+	}
+
+	return 1 - math.Sqrt(sum/float64(s.count))/maxDistance, true
+}
+
+// result make the Result of the match at (x, y)
+func (s *searcher) result(x, y int, score float64) Result {
+	return Result{
+		X:       x + s.origin.X,
+		Y:       y + s.origin.Y,
+		W:       s.needle.Width,
+		H:       s.needle.Height,
+		Score:   score,
+		Scale:   s.scale,
+		Ignored: s.needle.Width*s.needle.Height - s.count,
+	}
+}
+
+// better whether r is a better match than o,
+// the higher score wins, then the scale closer to 1
+func better(r, o Result) bool {
+	if r.Score != o.Score {
+		return r.Score > o.Score
+	}
+
+	return math.Abs(r.Scale-1) < math.Abs(o.Scale-1)
+}
+
+// Find find the first needle in the haystack, in rows order,
+// the best match of all points (and scales) is returned if the method
+// is MatchSSD or MatchNCC, or a scale range or the Pyramid is set
+//
+//	bitmap.Find(haystack, needle image.Image, &bitmap.Options{Tolerance: 0.1})
+func Find(haystack, needle image.Image, opt *Options) (Result, bool) {
+	if opt == nil {
+		opt = &Options{}
+	}
+
+	mode := scanFirst
+	if opt.best() {
+		mode = scanBest
+	}
+
+	list := newSearchers(haystack, needle, opt)
+	if opt.Pyramid > 0 {
+		var (
+			best  Result
+			found bool
+		)
+		for _, r := range scanPyramid(list, opt, false) {
+			if !found || better(r, best) {
+				best, found = r, true
+			}
+		}
+
+		return best, found
+	}
+
+	res := scanTiles(list, opt.Workers, mode)
+	if len(res) == 0 {
+		return Result{}, false
+	}
+
+	return res[0], true
+}
+
+// FindAll find every needle in the haystack, the matches do not overlap,
+// at most opt.Max results are returned if opt.Max > 0
+func FindAll(haystack, needle image.Image, opt *Options) []Result {
+	var res []Result
+
+	if opt == nil {
+		opt = &Options{}
+	}
+
+	list := newSearchers(haystack, needle, opt)
+	kept := newResultGrid(list)
+	keep := func(r Result) bool {
+		if !kept.overlaps(r) {
+			kept.add(r)
+			res = append(res, r)
+		}
+		return opt.Max <= 0 || len(res) < opt.Max
+	}
+
+	if opt.best() {
+		var all []Result
+		if opt.Pyramid > 0 {
+			all = scanPyramid(list, opt, true)
+		} else {
+			all = scanTiles(list, opt.Workers, scanAll)
+		}
+
+		// keep the best of the overlapping matches
+		sort.SliceStable(all, func(i, j int) bool { return better(all[i], all[j]) })
+		for _, r := range all {
+			if !keep(r) {
+				break
+			}
+		}
+	} else {
+		// keep the first of the overlapping matches in rows order,
+		// the scan stops at opt.Max
+		scanKeep(list, opt.Workers, keep)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Y != res[j].Y {
+			return res[i].Y < res[j].Y
+		}
+		return res[i].X < res[j].X
+	})
+
+	return res
+}
+
+// Count count of the needle in the haystack, see FindAll
+func Count(haystack, needle image.Image, opt *Options) int {
+	return len(FindAll(haystack, needle, opt))
+}
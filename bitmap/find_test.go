@@ -0,0 +1,604 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"runtime"
+	"testing"
+)
+
+// noise returns a bitmap filled with random colors
+func noise(w, h int, seed int64) *Bitmap {
+	r := rand.New(rand.NewSource(seed))
+	bit := New(w, h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			bit.SetRGB(x, y, uint8(r.Intn(256)), uint8(r.Intn(256)),
+				uint8(r.Intn(256)))
+		}
+	}
+
+	return bit
+}
+
+// crop copy a portion of the bitmap
+func crop(bit *Bitmap, x, y, w, h int) *Bitmap {
+	c := New(w, h)
+	for j := 0; j < h; j++ {
+		for i := 0; i < w; i++ {
+			r, g, b := bit.RGBAt(x+i, y+j)
+			c.SetRGB(i, j, r, g, b)
+		}
+	}
+
+	return c
+}
+
+func TestFind(t *testing.T) {
+	hay := noise(64, 48, 1)
+	needle := crop(hay, 40, 30, 8, 6)
+
+	res, ok := Find(hay, needle, nil)
+	if !ok || res.X != 40 || res.Y != 30 {
+		t.Fatalf("want (40, 30), got %v %v", res, ok)
+	}
+	if res.Score != 1 {
+		t.Errorf("exact match score %v", res.Score)
+	}
+
+	_, ok = Find(hay, needle, &Options{Rect: image.Rect(0, 0, 40, 48)})
+	if ok {
+		t.Error("found the needle outside of the search rect")
+	}
+
+	// a little noise should be accepted with a tolerance
+	r, g, b := needle.RGBAt(3, 3)
+	needle.SetRGB(3, 3, r^4, g, b)
+	if _, ok = Find(hay, needle, nil); ok {
+		t.Error("exact search matched a changed needle")
+	}
+	res, ok = Find(hay, needle, &Options{Tolerance: 0.05})
+	if !ok || res.X != 40 || res.Y != 30 || res.Score >= 1 {
+		t.Errorf("tolerance search got %v %v", res, ok)
+	}
+}
+
+func TestFindSubImage(t *testing.T) {
+	hay := noise(32, 32, 2).ToRGBA()
+	needle := hay.SubImage(image.Rect(20, 10, 25, 14))
+
+	res, ok := Find(hay.SubImage(image.Rect(8, 8, 32, 32)), needle, nil)
+	if !ok || res.Point() != image.Pt(20, 10) {
+		t.Fatalf("want (20, 10), got %v %v", res, ok)
+	}
+	if res.Rect() != image.Rect(20, 10, 25, 14) || res.Scale != 1 {
+		t.Errorf("bad rect %v, scale %v", res.Rect(), res.Scale)
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	hay := New(50, 20)
+	needle := noise(4, 4, 3)
+	for _, x := range []int{2, 20, 41} {
+		for j := 0; j < 4; j++ {
+			for i := 0; i < 4; i++ {
+				r, g, b := needle.RGBAt(i, j)
+				hay.SetRGB(x+i, 5+j, r, g, b)
+			}
+		}
+	}
+
+	res := FindAll(hay, needle, nil)
+	if len(res) != 3 || res[0].X != 2 || res[1].X != 20 || res[2].X != 41 {
+		t.Fatalf("FindAll got %v", res)
+	}
+	if n := len(FindAll(hay, needle, &Options{Max: 2})); n != 2 {
+		t.Errorf("Max 2, got %d", n)
+	}
+	if n := Count(hay, needle, nil); n != 3 {
+		t.Errorf("Count want 3, got %d", n)
+	}
+}
+
+func TestFindAllBlank(t *testing.T) {
+	// every 4 x 4 cell of a blank haystack matches
+	if n := Count(New(640, 480), New(4, 4), nil); n != 160*120 {
+		t.Errorf("Count want %d, got %d", 160*120, n)
+	}
+}
+
+func TestFindScale(t *testing.T) {
+	// a smooth needle, so the resampled one is close to the original
+	needle := New(12, 8)
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 12; x++ {
+			needle.SetRGB(x, y, uint8(x*20), uint8(y*30), 128)
+		}
+	}
+
+	hay := noise(80, 60, 4)
+	big := resample(needle, 18, 12)
+	for y := 0; y < 12; y++ {
+		for x := 0; x < 18; x++ {
+			r, g, b := big.RGBAt(x, y)
+			hay.SetRGB(30+x, 20+y, r, g, b)
+		}
+	}
+
+	if _, ok := Find(hay, needle, &Options{Tolerance: 0.1}); ok {
+		t.Fatal("found the needle without scaling")
+	}
+
+	opt := &Options{Tolerance: 0.1, MinScale: 0.5, MaxScale: 2, ScaleStep: 0.25}
+	res, ok := Find(hay, needle, opt)
+	if !ok || res.X != 30 || res.Y != 20 || res.Scale != 1.5 {
+		t.Fatalf("want (30, 20) x1.5, got %+v %v", res, ok)
+	}
+	if res.W != 18 || res.H != 12 {
+		t.Errorf("want size 18x12, got %dx%d", res.W, res.H)
+	}
+
+	if all := FindAll(hay, needle, opt); len(all) != 1 || all[0] != res {
+		t.Errorf("FindAll got %+v", all)
+	}
+}
+
+func TestScales(t *testing.T) {
+	s := (&Options{MinScale: 0.5, MaxScale: 2}).scales()
+	if len(s) != 16 || s[0] != 0.5 || s[15] < 1.99 {
+		t.Errorf("scales got %v", s)
+	}
+	if s := (&Options{}).scales(); len(s) != 1 || s[0] != 1 {
+		t.Errorf("default scales got %v", s)
+	}
+}
+
+func TestFindMask(t *testing.T) {
+	hay := noise(40, 30, 5)
+
+	// a round icon, the corners are transparent
+	icon := image.NewNRGBA(image.Rect(0, 0, 6, 6))
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 6; x++ {
+			if (x == 0 || x == 5) && (y == 0 || y == 5) {
+				continue
+			}
+			c := color.NRGBA{uint8(x * 40), uint8(y * 40), 200, 0xff}
+			icon.SetNRGBA(x, y, c)
+			hay.SetRGB(12+x, 7+y, c.R, c.G, c.B)
+		}
+	}
+
+	if m := AlphaMask(icon); m == nil || m.Ignored() != 4 {
+		t.Fatalf("AlphaMask got %v", m)
+	}
+
+	res, ok := Find(hay, icon, nil)
+	if !ok || res.X != 12 || res.Y != 7 || res.Ignored != 4 || res.Score != 1 {
+		t.Fatalf("want (12, 7), got %+v %v", res, ok)
+	}
+
+	// the same icon without alpha (e.g. a bmp) and an explicit mask
+	opaque := FromImage(icon)
+	if _, ok = Find(hay, opaque, nil); ok {
+		t.Error("found the opaque icon without a mask")
+	}
+
+	maskImg := image.NewGray(image.Rect(0, 0, 6, 6))
+	for i := range maskImg.Pix {
+		maskImg.Pix[i] = 0xff
+	}
+	for _, p := range []image.Point{{0, 0}, {5, 0}, {0, 5}, {5, 5}} {
+		maskImg.SetGray(p.X, p.Y, color.Gray{})
+	}
+
+	res, ok = Find(hay, opaque, &Options{Mask: MaskFromImage(maskImg)})
+	if !ok || res.X != 12 || res.Y != 7 || res.Ignored != 4 {
+		t.Errorf("explicit mask got %+v %v", res, ok)
+	}
+
+	// the empty mask is ignored, the short Pix finds nothing
+	res, ok = Find(hay, icon, &Options{Mask: &Mask{}})
+	if !ok || res.X != 12 || res.Y != 7 || res.Ignored != 4 {
+		t.Errorf("empty mask got %+v %v", res, ok)
+	}
+	if _, ok = Find(hay, icon, &Options{Mask: &Mask{Width: 6, Height: 6}}); ok {
+		t.Error("found with a short mask")
+	}
+	if _, ok = Find(hay, opaque, &Options{Mask: NewMask(5, 6)}); ok {
+		t.Error("found with a mask of the other size")
+	}
+}
+
+func TestFindMethod(t *testing.T) {
+	hay := noise(48, 40, 6)
+	needle := crop(hay, 20, 15, 10, 8)
+
+	// brighten and stretch the haystack, like a gamma shift between machines
+	shifted := New(hay.Width, hay.Height)
+	for y := 0; y < hay.Height; y++ {
+		for x := 0; x < hay.Width; x++ {
+			r, g, b := hay.RGBAt(x, y)
+			shifted.SetRGB(x, y, uint8(int(r)*3/4+40), uint8(int(g)*3/4+40),
+				uint8(int(b)*3/4+40))
+		}
+	}
+
+	if _, ok := Find(shifted, needle, &Options{Tolerance: 0.1}); ok {
+		t.Error("tolerance search matched the shifted haystack")
+	}
+
+	res, ok := Find(shifted, needle, &Options{Method: MatchNCC, Tolerance: 0.05})
+	if !ok || res.X != 20 || res.Y != 15 || res.Score < 0.99 {
+		t.Fatalf("NCC want (20, 15), got %+v %v", res, ok)
+	}
+
+	res, ok = Find(hay, needle, &Options{Method: MatchSSD, Tolerance: 0.3})
+	if !ok || res.X != 20 || res.Y != 15 || res.Score != 1 {
+		t.Fatalf("SSD want (20, 15), got %+v %v", res, ok)
+	}
+
+	res, ok = Find(hay, needle, &Options{Method: MatchExact, Tolerance: 1})
+	if !ok || res.X != 20 || res.Y != 15 {
+		t.Fatalf("exact want (20, 15), got %+v %v", res, ok)
+	}
+
+	found := false
+	for _, r := range FindAll(shifted, needle, &Options{Method: MatchNCC, Tolerance: 0.5}) {
+		if r.Score < 0.5 {
+			t.Errorf("NCC FindAll score %v < 0.5", r.Score)
+		}
+		found = found || r.Point() == image.Pt(20, 15)
+	}
+	if !found {
+		t.Error("NCC FindAll missed the needle")
+	}
+
+	// the flat needle on a flat haystack, scored by the mean colors
+	white := New(20, 20)
+	fill(white, white.Bounds(), 0xffffff)
+	red := New(4, 4)
+	fill(red, red.Bounds(), 0xff0000)
+	if res, ok := Find(white, red, &Options{Method: MatchNCC, Tolerance: 0.1}); ok {
+		t.Errorf("NCC flat red matched white, got %+v", res)
+	}
+
+	fill(white, image.Rect(8, 6, 12, 10), 0xff0000)
+	res, ok = Find(white, red, &Options{Method: MatchNCC, Tolerance: 0.1})
+	if !ok || res.Point() != image.Pt(8, 6) || res.Score != 1 {
+		t.Errorf("NCC flat red want (8, 6), got %+v %v", res, ok)
+	}
+}
+
+func TestFindWorkers(t *testing.T) {
+	hay := New(120, 90)
+	needle := noise(6, 5, 7)
+	for _, p := range []image.Point{{100, 80}, {3, 60}, {50, 60}, {70, 20}} {
+		for j := 0; j < 5; j++ {
+			for i := 0; i < 6; i++ {
+				r, g, b := needle.RGBAt(i, j)
+				hay.SetRGB(p.X+i, p.Y+j, r, g, b)
+			}
+		}
+	}
+
+	for _, opt := range []Options{
+		{},
+		{Tolerance: 0.2},
+		{Method: MatchSSD, Tolerance: 0.2},
+		{Method: MatchNCC, Tolerance: 0.3},
+		{MinScale: 0.8, MaxScale: 1.2, Tolerance: 0.2},
+		{Max: 1},
+		{Max: 2},
+		{Max: 3, Tolerance: 0.2},
+	} {
+		seq := opt
+		want, wok := Find(hay, needle, &seq)
+		wantAll := FindAll(hay, needle, &seq)
+
+		for _, workers := range []int{2, 3, 8, 64} {
+			par := opt
+			par.Workers = workers
+
+			got, ok := Find(hay, needle, &par)
+			if ok != wok || got != want {
+				t.Errorf("%+v: Find want %+v, got %+v", par, want, got)
+			}
+
+			all := FindAll(hay, needle, &par)
+			if len(all) != len(wantAll) {
+				t.Errorf("%+v: FindAll want %v, got %v", par, wantAll, all)
+				continue
+			}
+			for i := range all {
+				if all[i] != wantAll[i] {
+					t.Errorf("%+v: FindAll want %v, got %v", par, wantAll, all)
+					break
+				}
+			}
+		}
+	}
+}
+
+// screen4K returns a 4K haystack with the needle near the bottom right
+func screen4K(b *testing.B) (*Bitmap, *Bitmap) {
+	hay := New(3840, 2160)
+	for y := 0; y < hay.Height; y++ {
+		for x := 0; x < hay.Width; x++ {
+			hay.SetRGB(x, y, uint8(x), uint8(y), uint8(x^y))
+		}
+	}
+
+	needle := noise(32, 32, 8)
+	for j := 0; j < 32; j++ {
+		for i := 0; i < 32; i++ {
+			r, g, b := needle.RGBAt(i, j)
+			hay.SetRGB(3700+i, 2000+j, r, g, b)
+		}
+	}
+
+	b.ResetTimer()
+	return hay, needle
+}
+
+func benchmarkFind(b *testing.B, workers int) {
+	hay, needle := screen4K(b)
+	opt := &Options{Tolerance: 0.05, Workers: workers}
+
+	for i := 0; i < b.N; i++ {
+		if _, ok := Find(hay, needle, opt); !ok {
+			b.Fatal("not found")
+		}
+	}
+}
+
+// findBaseline is the sequential search before the tiles,
+// the baseline of the Workers benchmarks
+func findBaseline(hay, needle image.Image, opt *Options) (Result, bool) {
+	for _, s := range newSearchers(hay, needle, opt) {
+		for y := s.rect.Min.Y; y < s.rect.Max.Y; y++ {
+			for x := s.rect.Min.X; x < s.rect.Max.X; x++ {
+				if score, ok := s.match(x, y); ok {
+					return s.result(x, y, score), true
+				}
+			}
+		}
+	}
+
+	return Result{}, false
+}
+
+func BenchmarkFind4KBaseline(b *testing.B) {
+	hay, needle := screen4K(b)
+	opt := &Options{Tolerance: 0.05}
+
+	for i := 0; i < b.N; i++ {
+		if _, ok := findBaseline(hay, needle, opt); !ok {
+			b.Fatal("not found")
+		}
+	}
+}
+
+func BenchmarkFind4K(b *testing.B)           { benchmarkFind(b, 1) }
+func BenchmarkFind4KWorkers4(b *testing.B)   { benchmarkFind(b, 4) }
+func BenchmarkFind4KWorkersCPU(b *testing.B) { benchmarkFind(b, runtime.NumCPU()) }
+
+// benchmarkFindAllMax FindAll of a blank 4K haystack, every 4 x 4 cell
+// matches, Max 1 stops at the first match
+func benchmarkFindAllMax(b *testing.B, workers int) {
+	hay, needle := New(3840, 2160), New(4, 4)
+	opt := &Options{Max: 1, Workers: workers}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if n := len(FindAll(hay, needle, opt)); n != 1 {
+			b.Fatalf("want 1 result, got %d", n)
+		}
+	}
+}
+
+func BenchmarkFindAll4KMax1(b *testing.B)         { benchmarkFindAllMax(b, 1) }
+func BenchmarkFindAll4KMax1Workers4(b *testing.B) { benchmarkFindAllMax(b, 4) }
+
+func TestFindPyramid(t *testing.T) {
+	hay := noise(200, 150, 9)
+	needle := crop(hay, 123, 77, 40, 30)
+
+	for _, opt := range []Options{
+		{Pyramid: 2},
+		{Pyramid: 3, Tolerance: 0.05, Workers: 3},
+		{Pyramid: 2, Method: MatchNCC, Tolerance: 0.1},
+		// the needle is too small for 5 levels, the levels are reduced
+		{Pyramid: 5},
+	} {
+		res, ok := Find(hay, needle, &opt)
+		if !ok || res.X != 123 || res.Y != 77 || res.Score < 0.99 {
+			t.Errorf("%+v: want (123, 77), got %+v %v", opt, res, ok)
+		}
+	}
+
+	// the whole needle is smaller than the coarse level
+	small := crop(hay, 10, 20, 5, 5)
+	res, ok := Find(hay, small, &Options{Pyramid: 2})
+	if !ok || res.X != 10 || res.Y != 20 {
+		t.Errorf("small needle want (10, 20), got %+v %v", res, ok)
+	}
+
+	two := New(160, 120)
+	for _, p := range []image.Point{{8, 8}, {100, 70}} {
+		for j := 0; j < 30; j++ {
+			for i := 0; i < 40; i++ {
+				r, g, b := needle.RGBAt(i, j)
+				two.SetRGB(p.X+i, p.Y+j, r, g, b)
+			}
+		}
+	}
+
+	for _, opt := range []Options{
+		{Pyramid: 2},
+		{Pyramid: 2, Metric: MetricChannel, Tolerance: 10},
+		// no RGB bound, searched without the pyramid
+		{Pyramid: 2, Metric: MetricCIE76, Tolerance: 5},
+	} {
+		all := FindAll(two, needle, &opt)
+		if len(all) != 2 || all[0].Point() != image.Pt(8, 8) ||
+			all[1].Point() != image.Pt(100, 70) {
+			t.Errorf("%+v: FindAll got %+v", opt, all)
+		}
+	}
+	for m, want := range map[Metric]bool{MetricRGB: true, MetricChannel: true,
+		MetricCIE76: false, MetricCIEDE2000: false, MetricHSV: false} {
+		tol := 5.0
+		if m == MetricRGB {
+			tol = 0.1
+		}
+		s := newSearchers(two, needle, &Options{Metric: m, Tolerance: tol})[0]
+		if min, ok := s.minScore(); ok != want || ok && (min < 0 || min > 1) {
+			t.Errorf("metric %d minScore got %v %v", m, min, ok)
+		}
+	}
+
+	// more matches than the coarse candidates, Max 0 is unlimited
+	blank := New(200, 200)
+	for _, opt := range []Options{{}, {Pyramid: 1}, {Pyramid: 1, Workers: 4}} {
+		if n := Count(blank, New(10, 10), &opt); n != 400 {
+			t.Errorf("%+v: Count want 400, got %d", opt, n)
+		}
+	}
+	if n := Count(blank, New(10, 10), &Options{Pyramid: 1, Max: 30}); n != 30 {
+		t.Errorf("Pyramid Max 30, got %d", n)
+	}
+}
+
+// screenUI make a flat 4K screen with a 64x64 "window" needle, its
+// border matches the background, so the full search is slow
+func screenUI(b *testing.B) (*Bitmap, *Bitmap) {
+	hay := New(3840, 2160)
+	for y := 0; y < hay.Height; y++ {
+		for x := 0; x < hay.Width; x++ {
+			hay.SetRGB(x, y, 0xf0, 0xf0, 0xf0)
+		}
+	}
+
+	needle := noise(64, 64, 10)
+	for j := 0; j < 64; j++ {
+		for i := 0; i < 64; i++ {
+			if i < 4 || j < 4 || i >= 60 || j >= 60 {
+				needle.SetRGB(i, j, 0xf0, 0xf0, 0xf0)
+			}
+			r, g, b := needle.RGBAt(i, j)
+			hay.SetRGB(3000+i, 1800+j, r, g, b)
+		}
+	}
+
+	b.ResetTimer()
+	return hay, needle
+}
+
+func benchmarkFindUI(b *testing.B, pyramid int) {
+	hay, needle := screenUI(b)
+	opt := &Options{Tolerance: 0.05, Pyramid: pyramid}
+
+	for i := 0; i < b.N; i++ {
+		if r, ok := Find(hay, needle, opt); !ok || r.X != 3000 || r.Y != 1800 {
+			b.Fatal("not found")
+		}
+	}
+}
+
+func BenchmarkFind4KUI(b *testing.B)        { benchmarkFindUI(b, 0) }
+func BenchmarkFind4KUIPyramid(b *testing.B) { benchmarkFindUI(b, 2) }
+
+// themed draw a 16x16 icon at (x, y) with the fg color on the bg color
+func themed(bit *Bitmap, x, y int, fg, bg uint8, shape func(i, j int) bool) {
+	for j := 0; j < 16; j++ {
+		for i := 0; i < 16; i++ {
+			c := bg
+			if shape(i, j) {
+				c = fg
+			}
+			bit.SetRGB(x+i, y+j, c, c, c)
+		}
+	}
+}
+
+func TestFindFilter(t *testing.T) {
+	ring := func(i, j int) bool {
+		d := (i-8)*(i-8) + (j-8)*(j-8)
+		return d >= 16 && d <= 36 || i == j && i > 8
+	}
+	bar := func(i, j int) bool { return i >= 4 && i < 12 && j >= 6 && j < 10 }
+
+	// the light theme screen, the needle is the dark theme icon
+	hay := New(96, 64)
+	for y := 0; y < hay.Height; y++ {
+		for x := 0; x < hay.Width; x++ {
+			hay.SetRGB(x, y, 0xf5, 0xf5, 0xf5)
+		}
+	}
+	themed(hay, 10, 8, 0x20, 0xf5, bar)
+	themed(hay, 60, 40, 0x20, 0xf5, ring)
+
+	needle := New(16, 16)
+	themed(needle, 0, 0, 0xe0, 0x20, ring)
+
+	if _, ok := Find(hay, needle, &Options{Tolerance: 0.2}); ok {
+		t.Error("the dark icon matched the light screen colors")
+	}
+
+	for _, opt := range []Options{
+		{Filter: FilterEdge, Tolerance: 0.1},
+		{Filter: FilterEdge, Method: MatchNCC, Tolerance: 0.1},
+		// only the rect and its 1 pixel border are filtered
+		{Filter: FilterEdge, Tolerance: 0.1, Rect: image.Rect(60, 40, 76, 56)},
+		{Filter: FilterEdge, Tolerance: 0.1, Rect: image.Rect(50, 30, 96, 64)},
+	} {
+		res, ok := Find(hay, needle, &opt)
+		if !ok || res.X != 60 || res.Y != 40 || res.Ignored != 60 {
+			t.Errorf("%+v: want (60, 40), got %+v %v", opt, res, ok)
+		}
+	}
+
+	// the same luminance side, the colors differ
+	needle = New(16, 16)
+	themed(needle, 0, 0, 0x50, 0xc0, ring)
+	res, ok := Find(hay, needle, &Options{Filter: FilterThreshold})
+	if !ok || res.X != 60 || res.Y != 40 {
+		t.Errorf("threshold want (60, 40), got %+v %v", res, ok)
+	}
+	level := uint8(0xd0)
+	if _, ok := Find(hay, needle, &Options{Filter: FilterThreshold,
+		Threshold: &level}); ok {
+		t.Error("threshold 0xd0 matched")
+	}
+	// every pixel is white at the level 0
+	level = 0
+	if res, ok = Find(hay, needle, &Options{Filter: FilterThreshold,
+		Threshold: &level}); !ok || res.X != 0 || res.Y != 0 {
+		t.Errorf("threshold 0 want (0, 0), got %+v %v", res, ok)
+	}
+
+	hay.SetRGB(0, 0, 0xff, 0, 0)
+	g := Preprocess(hay, &Options{Filter: FilterGray})
+	if r, g, b := g.RGBAt(0, 0); r != 76 || g != 76 || b != 76 {
+		t.Errorf("gray of red got %d %d %d", r, g, b)
+	}
+	e := Preprocess(hay, &Options{Filter: FilterEdge})
+	if r, _, _ := e.RGBAt(30, 30); r != 0 {
+		t.Errorf("edge of the flat area got %d", r)
+	}
+	if r, _, _ := e.RGBAt(14, 15); r == 0 {
+		t.Error("no edge at the bar")
+	}
+}
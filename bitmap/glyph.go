@@ -0,0 +1,541 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"errors"
+	"image"
+	"io/ioutil"
+	"math"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// Errors of the glyph training
+var (
+	ErrGlyphEmpty = errors.New("Glyph image has no ink")
+	ErrGlyphCount = errors.New("Glyph count does not match the atlas")
+)
+
+const (
+	// defaultMinConfidence is the GlyphOptions.MinConfidence default
+	defaultMinConfidence = 0.5
+	// defaultSpace is the space gap of the line height
+	defaultSpace = 0.5
+)
+
+// Ink is the glyph ink color, see GlyphOptions.Ink
+type Ink int
+
+const (
+	// InkAuto the trained glyphs are the dark ink, they are cropped
+	// to the ink and have no background; Recognize takes the color of
+	// the most border pixels as the background, so both the dark on
+	// light and the light on dark text are read
+	InkAuto Ink = iota
+	// InkDark the dark text on a light background
+	InkDark
+	// InkLight the light text on a dark background
+	InkLight
+)
+
+// GlyphOptions is the glyph recognizer options
+type GlyphOptions struct {
+	// Threshold the ink luminance level, 0 is the automatic (Otsu)
+	// level, or 128 for the flat image (e.g. a solid "." glyph)
+	Threshold uint8
+	// Ink the ink color of the glyphs and the text, default InkAuto
+	Ink Ink
+	// MinConfidence 0.0 - 1.0, the glyphs below are Unknown, 0 is 0.5
+	MinConfidence float64
+	// Unknown the text of the unrecognized glyphs, default "?"
+	Unknown string
+	// Space the min gap of a space in pixels, 0 is half the line height
+	Space int
+}
+
+// Char is a recognized glyph, the spaces and the line breaks are
+// the chars of the confidence 1
+type Char struct {
+	Text string
+	// Rect the glyph rect in the image coordinates
+	Rect image.Rectangle
+	// Confidence the match score 0.0 - 1.0, 1 is the exact glyph
+	Confidence float64
+}
+
+// glyph is a trained glyph, the black ink on white
+// cropped to the ink
+type glyph struct {
+	text   string
+	img    *Bitmap
+	aspect float64
+}
+
+// Recognizer reads the text of the trained glyphs, e.g. the digits
+// of a counter; the glyphs are matched at any size, but the text is
+// read best in the size and font of the training
+type Recognizer struct {
+	opt    GlyphOptions
+	glyphs []glyph
+	// maxW, maxH the size of the largest glyph
+	maxW, maxH int
+}
+
+// NewRecognizer create a recognizer without glyphs
+func NewRecognizer(opt *GlyphOptions) *Recognizer {
+	r := &Recognizer{}
+	if opt != nil {
+		r.opt = *opt
+	}
+	if r.opt.MinConfidence <= 0 {
+		r.opt.MinConfidence = defaultMinConfidence
+	}
+	if r.opt.Unknown == "" {
+		r.opt.Unknown = "?"
+	}
+
+	return r
+}
+
+// glyphNames are the file names of the glyphs not allowed
+// in the file names, see LoadRecognizer
+var glyphNames = map[string]string{
+	"slash":     "/",
+	"backslash": "\\",
+	"colon":     ":",
+	"star":      "*",
+	"question":  "?",
+	"quote":     "\"",
+	"lt":        "<",
+	"gt":        ">",
+	"pipe":      "|",
+	"dot":       ".",
+}
+
+// LoadRecognizer load the glyph images of the directory, the file
+// name is the text of the glyph: "7.png", "7_2.png" (the other sample
+// of 7), "a.bmp", "slash.png" ("/"; also backslash, colon, star,
+// question, quote, lt, gt, pipe and dot)
+func LoadRecognizer(dir string, opt *GlyphOptions) (*Recognizer, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r := NewRecognizer(opt)
+	for _, f := range files {
+		if f.IsDir() || TypeFromExtension(f.Name()) == InvalidImageType {
+			continue
+		}
+
+		name := strings.TrimSuffix(f.Name(), filepath.Ext(f.Name()))
+		if i := strings.LastIndex(name, "_"); i > 0 {
+			name = name[:i]
+		}
+		if text, ok := glyphNames[name]; ok {
+			name = text
+		}
+
+		img, err := OpenImage(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if err := r.Add(name, img); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Add add the glyph image of the text, the image is cropped to the ink
+func (r *Recognizer) Add(text string, img image.Image) error {
+	m := r.ink(img, true)
+	if m == nil {
+		return ErrGlyphEmpty
+	}
+
+	rect := m.trim(image.Rect(0, 0, m.w, m.h))
+	if rect.Empty() {
+		return ErrGlyphEmpty
+	}
+	r.add(text, m.bitmap(rect))
+
+	return nil
+}
+
+// AddAtlas add the glyphs of the font atlas image, e.g. the text
+// "0123456789" drawn in the font; the glyphs are in the reading
+// order of chars, the spaces of chars are skipped; every glyph must
+// be separated by the empty columns and be one piece
+func (r *Recognizer) AddAtlas(img image.Image, chars string) error {
+	m := r.ink(img, true)
+	if m == nil {
+		return ErrGlyphEmpty
+	}
+
+	var rects []image.Rectangle
+	for _, line := range m.lines() {
+		rects = append(rects, m.columns(line)...)
+	}
+
+	var texts []string
+	for _, c := range chars {
+		if !unicode.IsSpace(c) {
+			texts = append(texts, string(c))
+		}
+	}
+	if len(texts) != len(rects) {
+		return ErrGlyphCount
+	}
+
+	for i, rect := range rects {
+		r.add(texts[i], m.bitmap(rect))
+	}
+
+	return nil
+}
+
+func (r *Recognizer) add(text string, bit *Bitmap) {
+	r.glyphs = append(r.glyphs, glyph{text: text, img: bit,
+		aspect: float64(bit.Width) / float64(bit.Height)})
+	if bit.Width > r.maxW {
+		r.maxW = bit.Width
+	}
+	if bit.Height > r.maxH {
+		r.maxH = bit.Height
+	}
+}
+
+// Recognize read the text of the image, the lines are separated by
+// "\n"; the chars joined are the text
+//
+//	text, chars := r.Recognize(img)
+func (r *Recognizer) Recognize(img image.Image) (string, []Char) {
+	m := r.ink(img, false)
+	if m == nil {
+		return "", nil
+	}
+
+	var chars []Char
+	for i, line := range m.lines() {
+		if i > 0 {
+			chars = append(chars, Char{Text: "\n", Confidence: 1})
+		}
+
+		space := r.opt.Space
+		if space <= 0 {
+			space = int(math.Max(2, math.Ceil(float64(line.Dy())*defaultSpace)))
+		}
+		// the touching glyphs are split if they are wider than
+		// the widest glyph and only matched as the parts
+		scale := 1.0
+		if r.maxH > 0 {
+			scale = float64(line.Dy()) / float64(r.maxH)
+		}
+		wide := int(float64(r.maxW)*scale*1.25) + 1
+
+		cols := m.columns(line)
+		for j, rect := range cols {
+			if j > 0 && rect.Min.X-cols[j-1].Max.X >= space {
+				chars = append(chars, Char{Text: " ", Confidence: 1,
+					Rect: image.Rect(cols[j-1].Max.X, line.Min.Y, rect.Min.X, line.Max.Y)})
+			}
+
+			c := r.match(m, rect)
+			if c.Confidence < r.opt.MinConfidence && rect.Dx() > wide {
+				if parts := r.split(m, rect, scale); mean(parts) >= r.opt.MinConfidence {
+					chars = append(chars, parts...)
+					continue
+				}
+			}
+			chars = append(chars, c)
+		}
+	}
+
+	texts := make([]string, len(chars))
+	for i := range chars {
+		chars[i].Rect = chars[i].Rect.Add(m.origin)
+		texts[i] = chars[i].Text
+	}
+
+	return strings.Join(texts, ""), chars
+}
+
+// match returns the best glyph of the ink rect
+func (r *Recognizer) match(m *inkMap, rect image.Rectangle) Char {
+	var (
+		cand = m.bitmap(rect)
+		best = Char{Text: r.opt.Unknown, Rect: rect}
+	)
+
+	for _, g := range r.glyphs {
+		if s := g.score(cand); s > best.Confidence {
+			best.Text, best.Confidence = g.text, s
+		}
+	}
+	if best.Confidence < r.opt.MinConfidence {
+		best.Text = r.opt.Unknown
+	}
+
+	return best
+}
+
+// split split the touching glyphs of the ink rect from the left,
+// the best glyph of its width (at the scale of the line) is taken
+func (r *Recognizer) split(m *inkMap, rect image.Rectangle, scale float64) []Char {
+	var chars []Char
+	for x := rect.Min.X; x < rect.Max.X; {
+		var (
+			best  Char
+			width int
+		)
+		for _, g := range r.glyphs {
+			w := int(float64(g.img.Width)*scale + 0.5)
+			if w < 1 {
+				w = 1
+			}
+			if x+w > rect.Max.X {
+				w = rect.Max.X - x
+			}
+
+			piece := m.trim(image.Rect(x, rect.Min.Y, x+w, rect.Max.Y))
+			if piece.Empty() {
+				continue
+			}
+			if s := g.score(m.bitmap(piece)); s > best.Confidence || width == 0 {
+				best = Char{Text: g.text, Rect: piece, Confidence: s}
+				width = w
+			}
+		}
+		if width == 0 {
+			break
+		}
+
+		if best.Confidence < r.opt.MinConfidence {
+			best.Text = r.opt.Unknown
+		}
+		chars = append(chars, best)
+		x += width
+	}
+
+	return chars
+}
+
+// mean returns the mean confidence of the chars
+func mean(chars []Char) float64 {
+	if len(chars) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, c := range chars {
+		sum += c.Confidence
+	}
+
+	return sum / float64(len(chars))
+}
+
+// score returns the similarity of the glyph and the candidate, the
+// MatchSSD score of the candidate resized to the glyph, weighted by
+// the aspect ratios
+func (g glyph) score(cand *Bitmap) float64 {
+	a := float64(cand.Width) / float64(cand.Height)
+	ratio := math.Min(a, g.aspect) / math.Max(a, g.aspect)
+
+	if cand.Width != g.img.Width || cand.Height != g.img.Height {
+		cand = Resize(cand, g.img.Width, g.img.Height, InterpArea)
+	}
+	res, _ := Find(cand, g.img, &Options{Method: MatchSSD, Tolerance: 1})
+
+	return res.Score * ratio
+}
+
+// inkMap is the binary image, true is the ink
+type inkMap struct {
+	w, h   int
+	pix    []bool
+	origin image.Point
+}
+
+// ink returns the ink map of the image, nil if the image is empty;
+// train whether the image is a glyph to add, see InkAuto
+func (r *Recognizer) ink(img image.Image, train bool) *inkMap {
+	bit := FromImage(img)
+	if bit == nil || bit.Width == 0 || bit.Height == 0 {
+		return nil
+	}
+
+	var (
+		n    = bit.Width * bit.Height
+		ys   = make([]uint8, n)
+		hist [256]int
+		bpp  = int(bit.BytesPerPixel)
+	)
+	for y := 0; y < bit.Height; y++ {
+		row := bit.ImageBuffer[y*bit.Bytewidth:]
+		for x := 0; x < bit.Width; x++ {
+			v := luma(row[x*bpp:])
+			ys[y*bit.Width+x] = v
+			hist[v]++
+		}
+	}
+
+	level := r.opt.Threshold
+	if level == 0 {
+		level = otsu(&hist, n)
+	}
+
+	darkInk := r.opt.Ink != InkLight
+	if r.opt.Ink == InkAuto && !train {
+		// the background is the side of the most border pixels
+		dark, border := 0, 0
+		for i, v := range ys {
+			x, y := i%bit.Width, i/bit.Width
+			if x == 0 || y == 0 || x == bit.Width-1 || y == bit.Height-1 {
+				border++
+				if v < level {
+					dark++
+				}
+			}
+		}
+		darkInk = 2*dark <= border
+	}
+
+	m := &inkMap{w: bit.Width, h: bit.Height, pix: make([]bool, n),
+		origin: img.Bounds().Min}
+	for i, v := range ys {
+		m.pix[i] = (v < level) == darkInk
+	}
+
+	return m
+}
+
+// otsu returns the level of the max between class variance,
+// the dark class is the luminance < level; 128 if the image is flat
+func otsu(hist *[256]int, n int) uint8 {
+	var sum, sumB, wB float64
+	for v, c := range hist {
+		sum += float64(v * c)
+	}
+
+	level, best := 0, -1.0
+	for t, c := range hist {
+		wB += float64(c)
+		if wB == 0 {
+			continue
+		}
+		wF := float64(n) - wB
+		if wF == 0 {
+			break
+		}
+
+		sumB += float64(t * c)
+		d := sumB/wB - (sum-sumB)/wF
+		if v := wB * wF * d * d; v > best {
+			level, best = t, v
+		}
+	}
+	if best < 0 {
+		return 128
+	}
+
+	return uint8(level + 1)
+}
+
+// runs returns the [start, end) runs of the true indexes
+func runs(n int, has func(i int) bool) [][2]int {
+	var (
+		res   [][2]int
+		start = -1
+	)
+	for i := 0; i <= n; i++ {
+		in := i < n && has(i)
+		if in && start < 0 {
+			start = i
+		} else if !in && start >= 0 {
+			res = append(res, [2]int{start, i})
+			start = -1
+		}
+	}
+
+	return res
+}
+
+// lines returns the rows of the text lines, split by the empty rows
+func (m *inkMap) lines() []image.Rectangle {
+	var res []image.Rectangle
+	for _, r := range runs(m.h, func(y int) bool {
+		return m.any(image.Rect(0, y, m.w, y+1))
+	}) {
+		res = append(res, image.Rect(0, r[0], m.w, r[1]))
+	}
+
+	return res
+}
+
+// columns returns the glyph rects of the line, split by the empty
+// columns and cropped to the ink
+func (m *inkMap) columns(line image.Rectangle) []image.Rectangle {
+	var res []image.Rectangle
+	for _, r := range runs(line.Dx(), func(x int) bool {
+		x += line.Min.X
+		return m.any(image.Rect(x, line.Min.Y, x+1, line.Max.Y))
+	}) {
+		res = append(res, m.trim(image.Rect(line.Min.X+r[0], line.Min.Y,
+			line.Min.X+r[1], line.Max.Y)))
+	}
+
+	return res
+}
+
+// any whether the rect has ink
+func (m *inkMap) any(r image.Rectangle) bool {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if m.pix[y*m.w+x] {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// trim returns the ink bounds of the rect, empty if no ink
+func (m *inkMap) trim(r image.Rectangle) image.Rectangle {
+	var ink image.Rectangle
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if m.pix[y*m.w+x] {
+				ink = ink.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+
+	return ink
+}
+
+// bitmap returns the rect of the map, the black ink on white
+func (m *inkMap) bitmap(r image.Rectangle) *Bitmap {
+	bit := New(r.Dx(), r.Dy())
+	for y := 0; y < r.Dy(); y++ {
+		for x := 0; x < r.Dx(); x++ {
+			if !m.pix[(y+r.Min.Y)*m.w+x+r.Min.X] {
+				bit.SetRGB(x, y, 0xff, 0xff, 0xff)
+			}
+		}
+	}
+
+	return bit
+}
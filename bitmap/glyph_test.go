@@ -0,0 +1,190 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// font is the 3 x 5 digits font
+var font = map[rune]string{
+	'0': "111101101101111", '1': "010110010010111", '2': "111001111100111",
+	'3': "111001111001111", '4': "101101111001001", '5': "111100111001111",
+	'6': "111100111101111", '7': "111001001001001", '8': "111101111101111",
+	'9': "111101111001111", '.': "000000000000010", '-': "000000111000000",
+}
+
+// drawText draw the text in the font, the glyphs are gap pixels apart
+// and the space is 3 pixels; s is the pixel size
+func drawText(text string, s, gap int, fg, bg uint32) *Bitmap {
+	bit := New((len(text)*(3+gap)+2)*s, 7*s)
+	fill(bit, bit.Bounds(), bg)
+
+	x := 1
+	for _, c := range text {
+		if c != ' ' {
+			for i, p := range font[c] {
+				if p == '1' {
+					px, py := x+i%3, 1+i/3
+					fill(bit, image.Rect(px*s, py*s, (px+1)*s, (py+1)*s), fg)
+				}
+			}
+		}
+		x += 3 + gap
+	}
+
+	return bit
+}
+
+func digits(t *testing.T) *Recognizer {
+	r := NewRecognizer(nil)
+	err := r.AddAtlas(drawText("0123456789.-", 1, 1, 0, 0xffffff), "0123456789 . -")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r
+}
+
+func TestRecognize(t *testing.T) {
+	r := digits(t)
+
+	text, chars := r.Recognize(drawText("12.5 -380", 1, 1, 0x202020, 0xf0f0f0))
+	if text != "12.5 -380" || len(chars) != 9 {
+		t.Fatalf("Recognize got %q", text)
+	}
+	for _, c := range chars {
+		if c.Confidence < 0.99 {
+			t.Errorf("%q confidence got %v", c.Text, c.Confidence)
+		}
+	}
+	if chars[0].Rect != image.Rect(1, 1, 4, 6) || chars[2].Rect != image.Rect(10, 5, 11, 6) {
+		t.Errorf("rects got %v %v", chars[0].Rect, chars[2].Rect)
+	}
+
+	// the light on dark text, scaled, in a sub image
+	img := drawText("9 6", 3, 1, 0xffffff, 0x003366).ToRGBA().SubImage(image.Rect(1, 1, 42, 20))
+	if text, chars := r.Recognize(img); text != "9 6" || chars[0].Rect.Min != image.Pt(3, 3) {
+		t.Errorf("scaled got %q %v", text, chars[0].Rect)
+	}
+
+	// the touching glyphs
+	if text, _ := r.Recognize(drawText("4747", 1, 0, 0, 0xffffff)); text != "4747" {
+		t.Errorf("touching got %q", text)
+	}
+
+	two := New(20, 20)
+	fill(two, two.Bounds(), 0xffffff)
+	fill(two, image.Rect(2, 2, 8, 4), 0)
+	fill(two, image.Rect(2, 8, 12, 18), 0)
+	fill(two, image.Rect(3, 9, 11, 17), 0xffffff)
+	if text, chars := r.Recognize(two); text != "-\n?" || chars[2].Confidence >= 0.5 {
+		t.Errorf("lines got %q", text)
+	}
+
+	blank := New(5, 5)
+	if text, chars := r.Recognize(blank); text != "" || chars != nil {
+		t.Errorf("blank got %q", text)
+	}
+}
+
+func TestLoadRecognizer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "glyphs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, text := range map[string]string{"7.png": "7", "7_2.bmp": "7",
+		"dot.png": ".", "1.png": "1"} {
+		if err := Save(drawText(text, 2, 1, 0, 0xffffff), filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ioutil.WriteFile(filepath.Join(dir, "README"), []byte("glyphs"), 0644)
+
+	r, err := LoadRecognizer(dir, &GlyphOptions{Unknown: "_"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.glyphs) != 4 {
+		t.Errorf("glyphs got %d", len(r.glyphs))
+	}
+	if text, _ := r.Recognize(drawText("71.3", 2, 1, 0, 0xffffff)); text != "71._" {
+		t.Errorf("Recognize got %q", text)
+	}
+
+	if err := r.AddAtlas(drawText("12", 1, 1, 0, 0xffffff), "123"); err != ErrGlyphCount {
+		t.Errorf("AddAtlas got %v", err)
+	}
+	white := New(3, 3)
+	fill(white, white.Bounds(), 0xffffff)
+	if err := r.Add("x", white); err != ErrGlyphEmpty {
+		t.Errorf("Add got %v", err)
+	}
+}
+
+func TestAddCropped(t *testing.T) {
+	// the tightly cropped glyphs: a solid "." and a bold "8"
+	// of more ink than background
+	bold := []string{"11111", "11011", "11011", "11111", "11011", "11011", "11111"}
+	draw := func(bit *Bitmap, x, y int) {
+		for j, row := range bold {
+			for i, p := range row {
+				if p == '1' {
+					bit.SetRGB(x+i, y+j, 0, 0, 0)
+				}
+			}
+		}
+	}
+
+	eight := New(5, 7)
+	fill(eight, eight.Bounds(), 0xffffff)
+	draw(eight, 0, 0)
+	dot := New(2, 2)
+
+	r := NewRecognizer(nil)
+	if err := r.Add("8", eight); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Add(".", dot); err != nil {
+		t.Fatal(err)
+	}
+	if g := r.glyphs[0].img; g.Width != 5 || g.Height != 7 {
+		t.Errorf("bold 8 got %d x %d", g.Width, g.Height)
+	}
+
+	line := New(22, 11)
+	fill(line, line.Bounds(), 0xffffff)
+	draw(line, 2, 2)
+	fill(line, image.Rect(9, 7, 11, 9), 0)
+	draw(line, 13, 2)
+	text, chars := r.Recognize(line)
+	if text != "8.8" {
+		t.Fatalf("Recognize got %q", text)
+	}
+	for _, c := range chars {
+		if c.Confidence < 0.99 {
+			t.Errorf("%q confidence got %v", c.Text, c.Confidence)
+		}
+	}
+
+	// the light ink glyph
+	light := NewRecognizer(&GlyphOptions{Ink: InkLight})
+	fill(dot, dot.Bounds(), 0xffffff)
+	if err := light.Add(".", dot); err != nil {
+		t.Errorf("light Add got %v", err)
+	}
+}
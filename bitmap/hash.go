@@ -0,0 +1,187 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"sort"
+	"strconv"
+)
+
+// Hash is a 64 bit perceptual hash, the bit 63 is the first
+// (top left) bit of the 8 x 8 hash in rows order
+type Hash uint64
+
+// HashType is the perceptual hash algorithm
+type HashType int
+
+const (
+	// HashAverage the aHash, the 8 x 8 luminance is brighter
+	// than the mean; fast, sensitive to the gradients
+	HashAverage HashType = iota
+	// HashDifference the dHash, the right pixel of the 9 x 8 luminance
+	// is brighter than the left; robust to the brightness and contrast
+	HashDifference
+	// HashPerceptual the pHash, the 8 x 8 low frequencies of the 32 x 32
+	// DCT are greater than the median; the most robust, slower
+	HashPerceptual
+)
+
+// hashSize is the side of the 8 x 8 hash
+const hashSize = 8
+
+// lumas returns the w x h area resized luminance of the image
+func lumas(img image.Image, w, h int) []float64 {
+	bit := Resize(img, w, h, InterpArea)
+	if bit == nil {
+		return nil
+	}
+
+	l := make([]float64, w*h)
+	bpp := int(bit.BytesPerPixel)
+	for y := 0; y < h; y++ {
+		row := bit.ImageBuffer[y*bit.Bytewidth:]
+		for x := 0; x < w; x++ {
+			l[y*w+x] = float64(luma(row[x*bpp:]))
+		}
+	}
+
+	return l
+}
+
+// hashBits set the bits of the true values, the first is bit 63
+func hashBits(n int, fn func(i int) bool) Hash {
+	var h Hash
+	for i := 0; i < n; i++ {
+		h <<= 1
+		if fn(i) {
+			h |= 1
+		}
+	}
+
+	return h
+}
+
+// AHash returns the average hash of the image, 0 for an empty image
+func AHash(img image.Image) Hash {
+	l := lumas(img, hashSize, hashSize)
+	if l == nil {
+		return 0
+	}
+
+	var mean float64
+	for _, v := range l {
+		mean += v
+	}
+	mean /= float64(len(l))
+
+	return hashBits(len(l), func(i int) bool { return l[i] > mean })
+}
+
+// DHash returns the difference hash of the image, 0 for an empty image
+func DHash(img image.Image) Hash {
+	const w = hashSize + 1
+
+	l := lumas(img, w, hashSize)
+	if l == nil {
+		return 0
+	}
+
+	return hashBits(hashSize*hashSize, func(i int) bool {
+		y, x := i/hashSize, i%hashSize
+		return l[y*w+x+1] > l[y*w+x]
+	})
+}
+
+// PHash returns the DCT perceptual hash of the image,
+// 0 for an empty image
+func PHash(img image.Image) Hash {
+	const n = 4 * hashSize
+
+	l := lumas(img, n, n)
+	if l == nil {
+		return 0
+	}
+
+	// the 2D DCT-II, only the low frequencies
+	low := make([]float64, hashSize*n)
+	for u := 0; u < hashSize; u++ {
+		for y := 0; y < n; y++ {
+			var s float64
+			for x := 0; x < n; x++ {
+				s += l[y*n+x] * dctCos[u][x]
+			}
+			low[u*n+y] = s
+		}
+	}
+
+	coef := make([]float64, hashSize*hashSize)
+	for v := 0; v < hashSize; v++ {
+		for u := 0; u < hashSize; u++ {
+			var s float64
+			for y := 0; y < n; y++ {
+				s += low[u*n+y] * dctCos[v][y]
+			}
+			coef[v*hashSize+u] = s
+		}
+	}
+
+	sorted := append([]float64(nil), coef...)
+	sort.Float64s(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+
+	return hashBits(len(coef), func(i int) bool { return coef[i] > median })
+}
+
+// dctCos is the DCT-II cos table of the 32 samples,
+// dctCos[u][x] = cos((2x + 1) u pi / 64)
+var dctCos = func() (t [hashSize][4 * hashSize]float64) {
+	const n = 4 * hashSize
+	for u := range t {
+		for x := range t[u] {
+			t[u][x] = math.Cos(float64(2*x+1) * float64(u) * math.Pi / (2 * n))
+		}
+	}
+
+	return
+}()
+
+// ImageHash returns the perceptual hash of the type
+func ImageHash(img image.Image, t HashType) Hash {
+	switch t {
+	case HashDifference:
+		return DHash(img)
+	case HashPerceptual:
+		return PHash(img)
+	}
+
+	return AHash(img)
+}
+
+// Distance returns the Hamming distance of the hashes, 0 - 64;
+// e.g. <= 10 is the same picture for the pHash
+func (h Hash) Distance(o Hash) int {
+	return bits.OnesCount64(uint64(h ^ o))
+}
+
+// String returns the 16 hex digits of the hash
+func (h Hash) String() string {
+	return fmt.Sprintf("%016x", uint64(h))
+}
+
+// ParseHash parse the hex hash of Hash.String
+func ParseHash(s string) (Hash, error) {
+	h, err := strconv.ParseUint(s, 16, 64)
+	return Hash(h), err
+}
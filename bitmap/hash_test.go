@@ -0,0 +1,79 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+	"testing"
+)
+
+// scene returns a window like image: a title bar, a panel and
+// a button, the seed moves the panel and the button
+func scene(seed int) *Bitmap {
+	bit := New(96, 64)
+	fill(bit, bit.Bounds(), 0xe0e0e0)
+	fill(bit, image.Rect(0, 0, 96, 10), 0x204080)
+	fill(bit, image.Rect(6+seed*40, 16, 46+seed*40, 56), 0x606060)
+	fill(bit, image.Rect(60-seed*50, 44, 90-seed*50, 58), 0x30a030)
+
+	return bit
+}
+
+func TestHash(t *testing.T) {
+	half := New(16, 16)
+	fill(half, image.Rect(8, 0, 16, 16), 0xffffff)
+	if h := AHash(half); h != 0x0f0f0f0f0f0f0f0f {
+		t.Errorf("AHash got %v", h)
+	}
+	if h := DHash(half); h != 0x1818181818181818 {
+		t.Errorf("DHash got %v", h)
+	}
+
+	a, b := scene(0), scene(1)
+	big := Resize(a, 192, 128, InterpBilinear)
+	light := a.Copy()
+	for i := range light.ImageBuffer {
+		light.ImageBuffer[i] += 0x10
+	}
+
+	for _, typ := range []HashType{HashAverage, HashDifference, HashPerceptual} {
+		h := ImageHash(a, typ)
+		if d := h.Distance(ImageHash(big, typ)); d > 4 {
+			t.Errorf("%d: the resized distance got %d", typ, d)
+		}
+		if d := h.Distance(ImageHash(light, typ)); d > 4 {
+			t.Errorf("%d: the lighter distance got %d", typ, d)
+		}
+		if d := h.Distance(ImageHash(b, typ)); d < 10 {
+			t.Errorf("%d: the other distance got %d", typ, d)
+		}
+	}
+
+	if PHash(New(0, 0)) != 0 {
+		t.Error("the empty image hash")
+	}
+}
+
+func TestHashString(t *testing.T) {
+	h := Hash(0x0f0f0f0f0f0f0f0f)
+	if s := Hash(0xab).String(); s != "00000000000000ab" {
+		t.Errorf("String got %s", s)
+	}
+	if p, err := ParseHash(h.String()); err != nil || p != h {
+		t.Errorf("ParseHash got %v, %v", p, err)
+	}
+	if _, err := ParseHash("xyz"); err == nil {
+		t.Error("ParseHash of xyz")
+	}
+	if Hash(0).Distance(^Hash(0)) != 64 {
+		t.Error("Distance")
+	}
+}
@@ -0,0 +1,351 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+// ImageType is the image file type, the png and bmp
+// are the same as C.MMImageType, the others are Go only
+type ImageType uint16
+
+const (
+	// InvalidImageType unsupported image type
+	InvalidImageType ImageType = iota
+	// PNGImageType png image
+	PNGImageType
+	// BMPImageType bmp image
+	BMPImageType
+	// JPEGImageType jpeg image, see SaveOptions.Quality
+	JPEGImageType
+	// GIFImageType gif image, the colors are quantized to 256
+	GIFImageType
+	// TIFFImageType tiff image, saved with the Deflate compression
+	TIFFImageType
+	// PPMImageType binary (P6) ppm image, the alpha is dropped
+	PPMImageType
+	// PAMImageType pam (P7) image
+	PAMImageType
+)
+
+// DefaultQuality is the default JPEG quality
+const DefaultQuality = jpeg.DefaultQuality
+
+// Errors of the image types
+var (
+	ErrUnsupportedType = errors.New("Unsupported image type")
+	ErrDataURI         = errors.New("Invalid image data URI")
+)
+
+// mimeTypes are the MIME types of the image types
+var mimeTypes = [...]string{
+	PNGImageType:  "image/png",
+	BMPImageType:  "image/bmp",
+	JPEGImageType: "image/jpeg",
+	GIFImageType:  "image/gif",
+	TIFFImageType: "image/tiff",
+	PPMImageType:  "image/x-portable-pixmap",
+	PAMImageType:  "image/x-portable-arbitrarymap",
+}
+
+// MIME returns the MIME type of the image type, e.g. "image/png";
+// "" if the type is invalid
+func (t ImageType) MIME() string {
+	if int(t) >= len(mimeTypes) {
+		return ""
+	}
+
+	return mimeTypes[t]
+}
+
+// TypeFromExtension returns the image type of the file extension
+// or path, like imageTypeFromExtension
+func TypeFromExtension(path string) ImageType {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if ext == "" {
+		ext = strings.ToLower(path)
+	}
+
+	switch ext {
+	case "png":
+		return PNGImageType
+	case "bmp":
+		return BMPImageType
+	case "jpg", "jpeg":
+		return JPEGImageType
+	case "gif":
+		return GIFImageType
+	case "tif", "tiff":
+		return TIFFImageType
+	case "ppm":
+		return PPMImageType
+	case "pam":
+		return PAMImageType
+	default:
+		return InvalidImageType
+	}
+}
+
+// Open open the image file and return bitmap,
+// the type is detected from the file content
+func Open(path string) (*Bitmap, error) {
+	img, err := OpenImage(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromImage(img), nil
+}
+
+// OpenImage open and decode the image file (png, bmp, jpeg, gif, tiff,
+// ppm, pgm or pam), keep the original image.Image (and its alpha channel)
+func OpenImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	return img, err
+}
+
+// SaveOptions is the image save options
+type SaveOptions struct {
+	// Type the image type, InvalidImageType selects
+	// the type by the file extension
+	Type ImageType
+	// Quality the JPEG quality 1 - 100, 0 is DefaultQuality
+	Quality int
+}
+
+// Save save the image to the file, the type is selected by
+// the file extension if mtype is omitted
+//
+//	bitmap.Save(bit, "test.png")
+//	bitmap.Save(bit, "test", bitmap.BMPImageType)
+func Save(img image.Image, path string, mtype ...ImageType) error {
+	if len(mtype) > 0 && mtype[0] == InvalidImageType {
+		return ErrUnsupportedType
+	}
+
+	opt := &SaveOptions{}
+	if len(mtype) > 0 {
+		opt.Type = mtype[0]
+	}
+
+	return SaveWith(img, path, opt)
+}
+
+// SaveWith save the image to the file with the options
+//
+//	bitmap.SaveWith(bit, "test.jpg", &bitmap.SaveOptions{Quality: 60})
+func SaveWith(img image.Image, path string, opt *SaveOptions) error {
+	o := SaveOptions{}
+	if opt != nil {
+		o = *opt
+	}
+	if o.Type == InvalidImageType {
+		o.Type = TypeFromExtension(path)
+	}
+	if o.Type == InvalidImageType || o.Type > PAMImageType {
+		return ErrUnsupportedType
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = encode(f, img, &o)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// Encode write the image to w, in the opt.Type (PNG by default)
+//
+//	bitmap.Encode(w, bit, &bitmap.SaveOptions{Type: bitmap.BMPImageType})
+func Encode(w io.Writer, img image.Image, opt *SaveOptions) error {
+	o := SaveOptions{}
+	if opt != nil {
+		o = *opt
+	}
+	if o.Type == InvalidImageType {
+		o.Type = PNGImageType
+	}
+
+	return encode(w, img, &o)
+}
+
+// EncodeBytes returns the encoded image, see Encode
+func EncodeBytes(img image.Image, opt *SaveOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := Encode(&buf, img, opt); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Decode decode the image of r to the bitmap, the type is detected
+// from the content (png, bmp, jpeg, gif, tiff, ppm, pgm or pam)
+func Decode(r io.Reader) (*Bitmap, error) {
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromImage(img), nil
+}
+
+// DecodeBytes decode the encoded image to the bitmap, see Decode
+func DecodeBytes(data []byte) (*Bitmap, error) {
+	return Decode(bytes.NewReader(data))
+}
+
+// DataURI returns the "data:image/png;base64,..." URI of the image,
+// e.g. for the <img src> of the HTML reports; see Encode
+func DataURI(img image.Image, opt *SaveOptions) (string, error) {
+	t := PNGImageType
+	if opt != nil && opt.Type != InvalidImageType {
+		t = opt.Type
+	}
+
+	data, err := EncodeBytes(img, opt)
+	if err != nil {
+		return "", err
+	}
+
+	return "data:" + t.MIME() + ";base64," +
+		base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecodeDataURI decode the base64 image data URI to the bitmap
+func DecodeDataURI(uri string) (*Bitmap, error) {
+	i := strings.Index(uri, ",")
+	if !strings.HasPrefix(uri, "data:image/") || i < 0 ||
+		!strings.HasSuffix(uri[:i], ";base64") {
+		return nil, ErrDataURI
+	}
+
+	data, err := base64.StdEncoding.DecodeString(uri[i+1:])
+	if err != nil {
+		return nil, ErrDataURI
+	}
+
+	return DecodeBytes(data)
+}
+
+// encode write the image of the opt.Type to w
+func encode(w io.Writer, img image.Image, opt *SaveOptions) error {
+	if bit, ok := img.(*Bitmap); ok {
+		img = bit.ToRGBA()
+	}
+
+	switch opt.Type {
+	case PNGImageType:
+		return png.Encode(w, img)
+	case BMPImageType:
+		return bmp.Encode(w, img)
+	case JPEGImageType:
+		q := opt.Quality
+		if q <= 0 {
+			q = DefaultQuality
+		}
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: q})
+	case GIFImageType:
+		if p := paletted(img); p != nil {
+			img = p
+		}
+		return gif.Encode(w, img, nil)
+	case TIFFImageType:
+		return tiff.Encode(w, img, &tiff.Options{
+			Compression: tiff.Deflate, Predictor: true})
+	case PPMImageType:
+		return encodePNM(w, toNRGBA(img), false)
+	case PAMImageType:
+		return encodePNM(w, toNRGBA(img), true)
+	}
+
+	return ErrUnsupportedType
+}
+
+// paletted returns the image of its own colors if the colors are
+// not more than 256 (e.g. a flat UI capture), the image is not dithered;
+// nil if there are more colors
+func paletted(img image.Image) *image.Paletted {
+	var (
+		n   = toNRGBA(img)
+		b   = n.Rect
+		pal color.Palette
+		idx = make(map[uint32]uint8)
+		pix = make([]uint8, b.Dx()*b.Dy())
+	)
+
+	for y := 0; y < b.Dy(); y++ {
+		src := n.Pix[n.PixOffset(b.Min.X, b.Min.Y+y):]
+		for x := 0; x < b.Dx(); x++ {
+			p := src[x*4 : x*4+4]
+			c := uint32(p[0])<<24 | uint32(p[1])<<16 | uint32(p[2])<<8 | uint32(p[3])
+
+			i, ok := idx[c]
+			if !ok {
+				if len(pal) == 256 {
+					return nil
+				}
+				i = uint8(len(pal))
+				idx[c] = i
+				pal = append(pal, color.NRGBA{p[0], p[1], p[2], p[3]})
+			}
+			pix[y*b.Dx()+x] = i
+		}
+	}
+
+	return &image.Paletted{Pix: pix, Stride: b.Dx(), Rect: b, Palette: pal}
+}
+
+// toNRGBA convert the image to *image.NRGBA
+func toNRGBA(img image.Image) *image.NRGBA {
+	switch m := img.(type) {
+	case *image.NRGBA:
+		return m
+	case *image.RGBA:
+		// the same pixels if opaque
+		if m.Opaque() {
+			return &image.NRGBA{Pix: m.Pix, Stride: m.Stride, Rect: m.Rect}
+		}
+	}
+
+	b := img.Bounds()
+	n := image.NewNRGBA(b)
+	draw.Draw(n, b, img, b.Min, draw.Src)
+
+	return n
+}
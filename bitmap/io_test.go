@@ -0,0 +1,266 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTypeFromExtension(t *testing.T) {
+	types := map[string]ImageType{
+		"a.png": PNGImageType, "a.BMP": BMPImageType, "a.jpg": JPEGImageType,
+		"a.JPEG": JPEGImageType, "gif": GIFImageType, "a.tif": TIFFImageType,
+		"a.tiff": TIFFImageType, "a.ppm": PPMImageType, "a.pam": PAMImageType,
+		"a.webp": InvalidImageType,
+	}
+	for path, want := range types {
+		if got := TypeFromExtension(path); got != want {
+			t.Errorf("TypeFromExtension(%q) got %d, want %d", path, got, want)
+		}
+	}
+}
+
+func TestSaveOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bitmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	bit := pattern(33, 17, 32, 7)
+	for _, ext := range []string{"png", "bmp", "tiff", "ppm", "pam"} {
+		path := filepath.Join(dir, "lossless."+ext)
+		if err := Save(bit, path); err != nil {
+			t.Fatalf("Save %s: %v", ext, err)
+		}
+
+		got, err := Open(path)
+		if err != nil || !samePixels(got, bit) {
+			t.Errorf("Open %s got %v", ext, err)
+		}
+	}
+
+	// the lossy types, the flat blocks
+	flat := New(64, 32)
+	fill(flat, image.Rect(0, 0, 32, 32), 0xff0000)
+	fill(flat, image.Rect(32, 0, 64, 32), 0x3080f0)
+	for _, ext := range []string{"jpg", "gif"} {
+		path := filepath.Join(dir, "lossy."+ext)
+		if err := Save(flat, path); err != nil {
+			t.Fatalf("Save %s: %v", ext, err)
+		}
+
+		got, err := Open(path)
+		if err != nil || !Similar(got.HexAt(16, 16), 0xff0000, 0.1) ||
+			!Similar(got.HexAt(48, 16), 0x3080f0, 0.1) {
+			t.Errorf("Open %s got %v", ext, err)
+		}
+	}
+
+	// the explicit type and the quality
+	noisy := noise(128, 128, 3)
+	small, large := filepath.Join(dir, "q10"), filepath.Join(dir, "q95")
+	if err := SaveWith(noisy, small, &SaveOptions{Type: JPEGImageType,
+		Quality: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(noisy, large, JPEGImageType); err != nil {
+		t.Fatal(err)
+	}
+	s1, _ := os.Stat(small)
+	s2, _ := os.Stat(large)
+	if s1 == nil || s2 == nil || s1.Size() >= s2.Size() {
+		t.Errorf("the quality 10 jpeg is not smaller")
+	}
+
+	if err := Save(bit, filepath.Join(dir, "a.webp")); err != ErrUnsupportedType {
+		t.Errorf("Save webp got %v", err)
+	}
+	if err := Save(bit, filepath.Join(dir, "a.png"), InvalidImageType); err != ErrUnsupportedType {
+		t.Errorf("Save invalid type got %v", err)
+	}
+}
+
+func TestAlpha(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	img.Set(1, 1, color.NRGBA{200, 100, 50, 0x80})
+	img.Set(2, 0, color.NRGBA{10, 20, 30, 0xff})
+
+	for _, mtype := range []ImageType{TIFFImageType, PAMImageType} {
+		var buf bytes.Buffer
+		if err := encode(&buf, img, &SaveOptions{Type: mtype}); err != nil {
+			t.Fatal(err)
+		}
+
+		got, _, err := image.Decode(&buf)
+		if err != nil {
+			t.Fatalf("type %d: %v", mtype, err)
+		}
+		n, ok := got.(*image.NRGBA)
+		if !ok || !bytes.Equal(n.Pix, img.Pix) {
+			t.Errorf("type %d alpha got %v", mtype, got)
+		}
+	}
+}
+
+func TestEncodeBytes(t *testing.T) {
+	bit := pattern(7, 5, 24, 4)
+
+	for _, mtype := range []ImageType{InvalidImageType, BMPImageType, PAMImageType} {
+		data, err := EncodeBytes(bit, &SaveOptions{Type: mtype})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := DecodeBytes(data)
+		if err != nil || !samePixels(got, bit) {
+			t.Errorf("type %d got %v", mtype, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, bit, nil); err != nil || !bytes.HasPrefix(buf.Bytes(), []byte("\x89PNG")) {
+		t.Errorf("Encode got %v", err)
+	}
+	if got, err := Decode(&buf); err != nil || !samePixels(got, bit) {
+		t.Errorf("Decode got %v", err)
+	}
+	if _, err := EncodeBytes(bit, &SaveOptions{Type: PAMImageType + 1}); err != ErrUnsupportedType {
+		t.Errorf("the unknown type got %v", err)
+	}
+}
+
+func TestDataURI(t *testing.T) {
+	bit := pattern(4, 3, 32, 5)
+
+	uri, err := DataURI(bit, nil)
+	if err != nil || !strings.HasPrefix(uri, "data:image/png;base64,iVBORw0KGgo") {
+		t.Fatalf("DataURI got %.40s, %v", uri, err)
+	}
+	if got, err := DecodeDataURI(uri); err != nil || !samePixels(got, bit) {
+		t.Errorf("DecodeDataURI got %v", err)
+	}
+
+	uri, _ = DataURI(bit, &SaveOptions{Type: JPEGImageType, Quality: 90})
+	if !strings.HasPrefix(uri, "data:image/jpeg;base64,/9j/") {
+		t.Errorf("jpeg DataURI got %.40s", uri)
+	}
+
+	for _, bad := range []string{"image/png;base64,AAAA", "data:image/png,AAAA",
+		"data:image/png;base64,@@"} {
+		if _, err := DecodeDataURI(bad); err != ErrDataURI {
+			t.Errorf("%q got %v", bad, err)
+		}
+	}
+	if BMPImageType.MIME() != "image/bmp" || InvalidImageType.MIME() != "" ||
+		ImageType(99).MIME() != "" {
+		t.Error("MIME")
+	}
+}
+
+func TestDecodePNM(t *testing.T) {
+	// the comment and the maxval 15
+	pgm := "P5\n# gray\n2 1\n15\n\x00\x0f"
+	img, format, err := image.Decode(bytes.NewReader([]byte(pgm)))
+	if err != nil || format != "pgm" {
+		t.Fatalf("pgm got %v %v", format, err)
+	}
+	if g := img.(*image.Gray); g.Pix[0] != 0 || g.Pix[1] != 0xff {
+		t.Errorf("pgm got %v", g.Pix)
+	}
+
+	// 16 bit
+	ppm := "P6 1 1 65535\n\xff\xff\x80\x00\x00\x00"
+	img, _, err = image.Decode(bytes.NewReader([]byte(ppm)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := img.(*image.RGBA).Pix; c[0] != 0xff || c[1] != 0x80 || c[2] != 0 {
+		t.Errorf("16 bit ppm got %v", c)
+	}
+
+	pam := "P7\nWIDTH 1\nHEIGHT 1\nDEPTH 2\nMAXVAL 255\n" +
+		"TUPLTYPE GRAYSCALE_ALPHA\nENDHDR\n\x40\x80"
+	img, _, err = image.Decode(bytes.NewReader([]byte(pam)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := img.At(0, 0).(color.NRGBA); c != (color.NRGBA{0x40, 0x40, 0x40, 0x80}) {
+		t.Errorf("gray alpha pam got %v", c)
+	}
+
+	errs := map[string]error{
+		"P6 1 x 255\n":         ErrPPMHeader,
+		"P6 1 1 70000\n":       ErrPPMColorDepth,
+		"P6 2 1 255\n\x00\x00": ErrPPMPixelData,
+		"P7\nWIDTH 1\nHEIGHT 1\nDEPTH 5\nMAXVAL 255\nENDHDR\n": ErrPPMColorDepth,
+
+		// the huge header, rejected before the allocation
+		"P6\n60000 60000\n255\nabc": ErrPPMHeader,
+		"P6\n8000 8000\n255\nabc":   ErrPPMPixelData,
+	}
+	for str, want := range errs {
+		if _, err := decodePNM(bytes.NewReader([]byte(str))); err != want {
+			t.Errorf("decode %q got %v, want %v", str, err, want)
+		}
+	}
+}
+
+func TestDecodeTIFF(t *testing.T) {
+	// big endian, WhiteIsZero gray 3x2, PackBits
+	data := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 7}
+	entry := func(tag, typ uint16, val uint32) {
+		data = append(data, byte(tag>>8), byte(tag), byte(typ>>8), byte(typ),
+			0, 0, 0, 1)
+		if typ == 3 {
+			data = append(data, byte(val>>8), byte(val), 0, 0)
+		} else {
+			data = append(data, byte(val>>24), byte(val>>16), byte(val>>8), byte(val))
+		}
+	}
+	// the width, height, bits per sample, compression, photometric
+	// (the shorts) and the strip offset and count (the longs)
+	entry(256, 3, 3)
+	entry(257, 3, 2)
+	entry(258, 3, 8)
+	entry(259, 3, 32773)
+	entry(262, 3, 0)
+	entry(273, 4, 10+7*12+4)
+	entry(279, 4, 6)
+	data = append(data, 0, 0, 0, 0)
+	// the run of 3 zeros and the 3 literal bytes
+	data = append(data, 0xfe, 0, 2, 0xff, 0xf0, 0x0f)
+
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil || format != "tiff" {
+		t.Fatalf("tiff got %v %v", format, err)
+	}
+	want := []uint8{0xff, 0xff, 0xff, 0, 0x0f, 0xf0}
+	if g := img.(*image.Gray); !bytes.Equal(g.Pix, want) {
+		t.Errorf("tiff got %v, want %v", g.Pix, want)
+	}
+
+	// the huge size of the short data
+	data[10+8] = 0xff
+	data[10+12+8] = 0xff
+	if _, err := DecodeBytes(data); err == nil {
+		t.Error("the huge tiff should fail")
+	}
+	if _, err := DecodeBytes(data[:20]); err == nil {
+		t.Error("the short tiff should fail")
+	}
+}
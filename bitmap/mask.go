@@ -0,0 +1,148 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+	"image/color"
+)
+
+// Mask is the needle mask, the pixels not set are
+// "don't care" pixels and ignored by the search
+type Mask struct {
+	Width  int
+	Height int
+	// Pix is true for the pixels to compare, in rows order
+	Pix []bool
+}
+
+// NewMask create a mask with all pixels set
+func NewMask(w, h int) *Mask {
+	m := &Mask{Width: w, Height: h, Pix: make([]bool, w*h)}
+	for i := range m.Pix {
+		m.Pix[i] = true
+	}
+
+	return m
+}
+
+// Set whether the pixel at (x, y) is compared
+func (m *Mask) Set(x, y int, v bool) {
+	m.Pix[y*m.Width+x] = v
+}
+
+// Get whether the pixel at (x, y) is compared
+func (m *Mask) Get(x, y int) bool {
+	return m.Pix[y*m.Width+x]
+}
+
+// Ignored returns the count of the ignored pixels
+func (m *Mask) Ignored() int {
+	n := 0
+	for _, v := range m.Pix {
+		if !v {
+			n++
+		}
+	}
+
+	return n
+}
+
+// AlphaMask make a mask from the alpha channel of the image,
+// the fully transparent pixels are ignored;
+// return nil if the image has no transparent pixel
+func AlphaMask(img image.Image) *Mask {
+	if _, ok := img.(*Bitmap); ok || img == nil {
+		return nil
+	}
+	if o, ok := img.(interface {
+		Opaque() bool
+	}); ok && o.Opaque() {
+		return nil
+	}
+
+	r := img.Bounds()
+	m := NewMask(r.Dx(), r.Dy())
+	found := false
+
+	for y := 0; y < m.Height; y++ {
+		for x := 0; x < m.Width; x++ {
+			if _, _, _, a := img.At(x+r.Min.X, y+r.Min.Y).RGBA(); a == 0 {
+				m.Set(x, y, false)
+				found = true
+			}
+		}
+	}
+
+	if !found {
+		return nil
+	}
+
+	return m
+}
+
+// MaskFromImage make a mask from a mask image (e.g. a bmp file),
+// the dark pixels (gray < 128) and the transparent pixels are ignored
+func MaskFromImage(img image.Image) *Mask {
+	if img == nil {
+		return nil
+	}
+
+	r := img.Bounds()
+	m := NewMask(r.Dx(), r.Dy())
+
+	for y := 0; y < m.Height; y++ {
+		for x := 0; x < m.Width; x++ {
+			c := img.At(x+r.Min.X, y+r.Min.Y)
+			_, _, _, a := c.RGBA()
+			g := color.GrayModel.Convert(c).(color.Gray)
+			if a == 0 || g.Y < 128 {
+				m.Set(x, y, false)
+			}
+		}
+	}
+
+	return m
+}
+
+// OpenMask open the mask image file, see MaskFromImage
+func OpenMask(path string) (*Mask, error) {
+	img, err := OpenImage(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return MaskFromImage(img), nil
+}
+
+// empty whether the mask is the zero Mask
+func (m *Mask) empty() bool {
+	return m.Width == 0 && m.Height == 0 && len(m.Pix) == 0
+}
+
+// valid whether the size is positive and Pix is Width * Height
+func (m *Mask) valid() bool {
+	return m.Width > 0 && m.Height > 0 && len(m.Pix) == m.Width*m.Height
+}
+
+// resize resize the mask to w x h with the nearest neighbor
+func (m *Mask) resize(w, h int) *Mask {
+	dst := &Mask{Width: w, Height: h, Pix: make([]bool, w*h)}
+	for y := 0; y < h; y++ {
+		sy := clamp((2*y+1)*m.Height/(2*h), m.Height)
+		for x := 0; x < w; x++ {
+			sx := clamp((2*x+1)*m.Width/(2*w), m.Width)
+			dst.Pix[y*w+x] = m.Pix[sy*m.Width+sx]
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,317 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+	"math"
+)
+
+// Metric is the color distance metric, every metric has
+// its own tolerance units
+type Metric int
+
+const (
+	// MetricRGB the RGB Euclidean distance, as MMRGBColorSimilarToColor,
+	// the tolerance is 0.0 - 1.0 (the distance / 442)
+	MetricRGB Metric = iota
+	// MetricChannel the max difference of the r, g and b channels,
+	// the tolerance is 0 - 255 levels
+	MetricChannel
+	// MetricCIE76 the CIE76 ΔE*ab in the CIE Lab (D65),
+	// the tolerance is in ΔE, ~2.3 is the just noticeable difference
+	MetricCIE76
+	// MetricCIEDE2000 the CIEDE2000 ΔE00, the tolerance is in ΔE,
+	// more uniform than CIE76 for the saturated colors and the grays
+	MetricCIEDE2000
+	// MetricHSV the hue, saturation and value ranges (HSVRange)
+	// around the color, the tolerance is not used
+	MetricHSV
+)
+
+// HSVRange is the MetricHSV tolerance, the max differences
+type HSVRange struct {
+	// H the hue difference in degrees 0 - 180
+	H float64
+	// S, V the saturation and value differences 0.0 - 1.0
+	S, V float64
+}
+
+// comparer compares the colors with a metric, the colors are
+// converted once (e.g. to Lab) by conv and compared by within
+type comparer struct {
+	metric Metric
+	tol    float64
+	hsv    HSVRange
+}
+
+// newComparer returns nil for MetricRGB, it is the fast path
+// of the callers
+func newComparer(m Metric, tolerance float64, hsv HSVRange) *comparer {
+	if m == MetricRGB {
+		return nil
+	}
+
+	return &comparer{metric: m, tol: tolerance, hsv: hsv}
+}
+
+// conv convert the color to the space of the metric
+func (c *comparer) conv(r, g, b uint8) [3]float64 {
+	switch c.metric {
+	case MetricCIE76, MetricCIEDE2000:
+		l, a, bb := lab(r, g, b)
+		return [3]float64{l, a, bb}
+	case MetricHSV:
+		h, s, v := hsv(r, g, b)
+		return [3]float64{h, s, v}
+	}
+
+	return [3]float64{float64(r), float64(g), float64(b)}
+}
+
+// conv32 convert the color as conv, in float32 to save the memory
+func (c *comparer) conv32(r, g, b uint8) [3]float32 {
+	v := c.conv(r, g, b)
+	return [3]float32{float32(v[0]), float32(v[1]), float32(v[2])}
+}
+
+// within32 whether the conv32 colors are similar
+func (c *comparer) within32(x, y [3]float32) bool {
+	return c.within([3]float64{float64(x[0]), float64(x[1]), float64(x[2])},
+		[3]float64{float64(y[0]), float64(y[1]), float64(y[2])})
+}
+
+// convMap is the rect of a bitmap converted by a comparer, the
+// pixels are converted once per search instead of once per compare
+type convMap struct {
+	rect image.Rectangle
+	px   [][3]float32
+}
+
+// newConvMap convert the rect of the bitmap on workers goroutines
+func newConvMap(bit *Bitmap, r image.Rectangle, c *comparer, workers int) *convMap {
+	m := &convMap{rect: r, px: make([][3]float32, r.Dx()*r.Dy())}
+	if r.Empty() {
+		return m
+	}
+
+	n := 1
+	if workers > 1 {
+		n = workers * tilesPerWorker
+	}
+	if n > r.Dy() {
+		n = r.Dy()
+	}
+	step := (r.Dy() + n - 1) / n
+	bpp := int(bit.BytesPerPixel)
+
+	runTiles(n, workers, func(i int) {
+		for y := r.Min.Y + i*step; y < r.Min.Y+(i+1)*step && y < r.Max.Y; y++ {
+			row := bit.ImageBuffer[y*bit.Bytewidth:]
+			dst := m.px[(y-r.Min.Y)*r.Dx():]
+			for x := r.Min.X; x < r.Max.X; x++ {
+				px := row[x*bpp:]
+				dst[x-r.Min.X] = c.conv32(px[2], px[1], px[0])
+			}
+		}
+	})
+
+	return m
+}
+
+// within whether the converted colors are similar
+func (c *comparer) within(x, y [3]float64) bool {
+	switch c.metric {
+	case MetricCIE76:
+		return deltaE76(x, y) <= c.tol
+	case MetricCIEDE2000:
+		return deltaE2000(x, y) <= c.tol
+	case MetricHSV:
+		dh := math.Abs(x[0] - y[0])
+		if dh > 180 {
+			dh = 360 - dh
+		}
+		return dh <= c.hsv.H+eps && math.Abs(x[1]-y[1]) <= c.hsv.S+eps &&
+			math.Abs(x[2]-y[2]) <= c.hsv.V+eps
+	}
+
+	for i := 0; i < 3; i++ {
+		if math.Abs(x[i]-y[i]) > c.tol {
+			return false
+		}
+	}
+	return true
+}
+
+// linear is the sRGB to linear RGB table
+var linear = func() (t [256]float64) {
+	for i := range t {
+		v := float64(i) / 255
+		if v <= 0.04045 {
+			t[i] = v / 12.92
+		} else {
+			t[i] = math.Pow((v+0.055)/1.055, 2.4)
+		}
+	}
+
+	return
+}()
+
+// lab convert the sRGB color to the CIE Lab (D65)
+func lab(r, g, b uint8) (float64, float64, float64) {
+	lr, lg, lb := linear[r], linear[g], linear[b]
+
+	x := (0.4124564*lr + 0.3575761*lg + 0.1804375*lb) / 0.95047
+	y := 0.2126729*lr + 0.7151522*lg + 0.0721750*lb
+	z := (0.0193339*lr + 0.1191920*lg + 0.9503041*lb) / 1.08883
+
+	f := func(t float64) float64 {
+		if t > 216.0/24389 {
+			return math.Cbrt(t)
+		}
+		return (24389.0/27*t + 16) / 116
+	}
+
+	fx, fy, fz := f(x), f(y), f(z)
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+// hsv convert the color to the hue (degrees), saturation and value
+func hsv(r, g, b uint8) (float64, float64, float64) {
+	fr, fg, fb := float64(r)/255, float64(g)/255, float64(b)/255
+	max := math.Max(fr, math.Max(fg, fb))
+	min := math.Min(fr, math.Min(fg, fb))
+	d := max - min
+
+	var h float64
+	switch {
+	case d == 0:
+	case max == fr:
+		h = math.Mod((fg-fb)/d, 6)
+	case max == fg:
+		h = (fb-fr)/d + 2
+	default:
+		h = (fr-fg)/d + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+
+	s := 0.0
+	if max > 0 {
+		s = d / max
+	}
+
+	return h, s, max
+}
+
+func deltaE76(x, y [3]float64) float64 {
+	dl, da, db := x[0]-y[0], x[1]-y[1], x[2]-y[2]
+
+	return math.Sqrt(dl*dl + da*da + db*db)
+}
+
+// deltaE2000 the CIEDE2000 color difference, kL = kC = kH = 1
+func deltaE2000(x, y [3]float64) float64 {
+	const deg = math.Pi / 180
+
+	l1, a1, b1 := x[0], x[1], x[2]
+	l2, a2, b2 := y[0], y[1], y[2]
+
+	c1 := math.Hypot(a1, b1)
+	c2 := math.Hypot(a2, b2)
+	cm := math.Pow((c1+c2)/2, 7)
+	g := 0.5 * (1 - math.Sqrt(cm/(cm+math.Pow(25, 7))))
+
+	a1p, a2p := (1+g)*a1, (1+g)*a2
+	c1p, c2p := math.Hypot(a1p, b1), math.Hypot(a2p, b2)
+
+	hue := func(b, a float64) float64 {
+		if a == 0 && b == 0 {
+			return 0
+		}
+		h := math.Atan2(b, a) / deg
+		if h < 0 {
+			h += 360
+		}
+		return h
+	}
+	h1p, h2p := hue(b1, a1p), hue(b2, a2p)
+
+	dlp := l2 - l1
+	dcp := c2p - c1p
+
+	var dhp float64
+	if c1p*c2p != 0 {
+		dhp = h2p - h1p
+		if dhp > 180 {
+			dhp -= 360
+		} else if dhp < -180 {
+			dhp += 360
+		}
+	}
+	dHp := 2 * math.Sqrt(c1p*c2p) * math.Sin(dhp/2*deg)
+
+	lpm := (l1 + l2) / 2
+	cpm := (c1p + c2p) / 2
+
+	hpm := h1p + h2p
+	if c1p*c2p != 0 {
+		switch {
+		case math.Abs(h1p-h2p) <= 180:
+			hpm /= 2
+		case h1p+h2p < 360:
+			hpm = (hpm + 360) / 2
+		default:
+			hpm = (hpm - 360) / 2
+		}
+	}
+
+	t := 1 - 0.17*math.Cos((hpm-30)*deg) + 0.24*math.Cos(2*hpm*deg) +
+		0.32*math.Cos((3*hpm+6)*deg) - 0.20*math.Cos((4*hpm-63)*deg)
+	dTheta := 30 * math.Exp(-((hpm-275)/25)*((hpm-275)/25))
+	cpm7 := math.Pow(cpm, 7)
+	rc := 2 * math.Sqrt(cpm7/(cpm7+math.Pow(25, 7)))
+
+	l50 := (lpm - 50) * (lpm - 50)
+	sl := 1 + 0.015*l50/math.Sqrt(20+l50)
+	sc := 1 + 0.045*cpm
+	sh := 1 + 0.015*cpm*t
+	rt := -math.Sin(2*dTheta*deg) * rc
+
+	kl, kc, kh := dlp/sl, dcp/sc, dHp/sh
+	return math.Sqrt(kl*kl + kc*kc + kh*kh + rt*kc*kh)
+}
+
+// Lab returns the CIE Lab (D65) of the 0xRRGGBB color
+func Lab(color uint32) (l, a, b float64) {
+	return lab(uint8(color>>16), uint8(color>>8), uint8(color))
+}
+
+// HSV returns the hue (degrees 0 - 360), saturation
+// and value (0.0 - 1.0) of the 0xRRGGBB color
+func HSV(color uint32) (h, s, v float64) {
+	return hsv(uint8(color>>16), uint8(color>>8), uint8(color))
+}
+
+// DeltaE returns the ΔE of the 0xRRGGBB colors,
+// MetricCIEDE2000 if m is not MetricCIE76
+func DeltaE(c1, c2 uint32, m Metric) float64 {
+	l1, a1, b1 := Lab(c1)
+	l2, a2, b2 := Lab(c2)
+	x, y := [3]float64{l1, a1, b1}, [3]float64{l2, a2, b2}
+
+	if m == MetricCIE76 {
+		return deltaE76(x, y)
+	}
+	return deltaE2000(x, y)
+}
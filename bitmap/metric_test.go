@@ -0,0 +1,140 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+func near(a, b, d float64) bool {
+	return math.Abs(a-b) <= d
+}
+
+func TestDeltaE2000(t *testing.T) {
+	// Sharma, Wu and Dalal, the CIEDE2000 test data
+	for _, c := range []struct {
+		x, y [3]float64
+		want float64
+	}{
+		{[3]float64{50, 2.6772, -79.7751}, [3]float64{50, 0, -82.7485}, 2.0425},
+		{[3]float64{50, 0, 0}, [3]float64{50, -1, 2}, 2.3669},
+		{[3]float64{50, 2.49, -0.001}, [3]float64{50, -2.49, 0.0009}, 7.1792},
+		{[3]float64{50, 2.5, 0}, [3]float64{73, 25, -18}, 27.1492},
+		{[3]float64{2.0776, 0.0795, -1.135}, [3]float64{0.9033, -0.0636, -0.5514}, 0.9082},
+	} {
+		if d := deltaE2000(c.x, c.y); !near(d, c.want, 1e-4) {
+			t.Errorf("deltaE2000(%v, %v) = %.4f, want %.4f", c.x, c.y, d, c.want)
+		}
+	}
+}
+
+func TestColorSpaces(t *testing.T) {
+	if l, a, b := Lab(0xffffff); !near(l, 100, 1e-3) || !near(a, 0, 1e-3) ||
+		!near(b, 0, 1e-3) {
+		t.Errorf("Lab of white got %v %v %v", l, a, b)
+	}
+	if l, a, b := Lab(0xff0000); !near(l, 53.24, 0.01) || !near(a, 80.09, 0.01) ||
+		!near(b, 67.20, 0.01) {
+		t.Errorf("Lab of red got %v %v %v", l, a, b)
+	}
+	if h, s, v := HSV(0x3399ff); !near(h, 210, 1e-9) || !near(s, 0.8, 1e-9) ||
+		v != 1 {
+		t.Errorf("HSV got %v %v %v", h, s, v)
+	}
+	if h, _, _ := HSV(0xff0080); !near(h, 330, 0.2) {
+		t.Errorf("HSV hue got %v", h)
+	}
+
+	// the same RGB distance, the gray step is more visible
+	if DeltaE(0x808080, 0x8a8a8a, MetricCIEDE2000) <=
+		DeltaE(0x0000ff, 0x0a00ff, MetricCIEDE2000) {
+		t.Error("the gray difference should be larger")
+	}
+	if d := DeltaE(0x808080, 0x8a8a8a, MetricCIE76); !near(d, 3.9, 0.2) {
+		t.Errorf("CIE76 got %v", d)
+	}
+}
+
+func TestColorMetric(t *testing.T) {
+	bit := New(10, 1)
+	for x, c := range []uint32{0x3399ff, 0x2277cc, 0x3399f5, 0x99ccff,
+		0x39a0ff, 0xff9933, 0x808080, 0x858585} {
+		fill(bit, image.Rect(x, 0, x+1, 1), c)
+	}
+
+	for _, c := range []struct {
+		opt  ColorOptions
+		want int
+	}{
+		// the darker, the same hue and saturation
+		{ColorOptions{Metric: MetricHSV, HSV: HSVRange{H: 10, S: 0.1, V: 0.3}}, 4},
+		{ColorOptions{Metric: MetricHSV, HSV: HSVRange{H: 10, S: 0.1}}, 2},
+		{ColorOptions{Metric: MetricChannel, Tolerance: 10}, 3},
+		{ColorOptions{Metric: MetricChannel, Tolerance: 9}, 2},
+		{ColorOptions{Metric: MetricCIEDE2000, Tolerance: 3}, 3},
+		{ColorOptions{Metric: MetricCIE76, Tolerance: 0}, 1},
+	} {
+		if n := CountColor(bit, 0x3399ff, &c.opt); n != c.want {
+			t.Errorf("%+v: count %d, want %d", c.opt, n, c.want)
+		}
+	}
+
+	if n := CountColor(bit, 0x808080, &ColorOptions{Metric: MetricCIEDE2000,
+		Tolerance: 1}); n != 1 {
+		t.Errorf("the gray count %d", n)
+	}
+}
+
+func TestFindMetric(t *testing.T) {
+	hay := noise(40, 30, 11)
+	needle := crop(hay, 20, 10, 8, 8)
+	for j := 0; j < 8; j++ {
+		for i := 0; i < 8; i++ {
+			r, g, b := needle.RGBAt(i, j)
+			if r < 250 {
+				r += 5
+			}
+			needle.SetRGB(i, j, r, g, b)
+		}
+	}
+
+	for _, c := range []struct {
+		opt Options
+		ok  bool
+	}{
+		{Options{Metric: MetricChannel, Tolerance: 5}, true},
+		{Options{Metric: MetricChannel, Tolerance: 4}, false},
+		{Options{Metric: MetricCIEDE2000, Tolerance: 5}, true},
+		{Options{Metric: MetricCIEDE2000, Tolerance: 0.1}, false},
+		{Options{Metric: MetricHSV, HSV: HSVRange{H: 30, S: 0.1, V: 0.05}}, true},
+		{Options{Metric: MetricHSV}, false},
+	} {
+		res, ok := Find(hay, needle, &c.opt)
+		if ok != c.ok || ok && (res.X != 20 || res.Y != 10) {
+			t.Errorf("%+v: got %+v %v", c.opt, res, ok)
+		}
+	}
+}
+
+func BenchmarkFindCIEDE2000(b *testing.B) {
+	hay := noise(640, 480, 12)
+	needle := crop(hay, 600, 440, 32, 32)
+	opt := &Options{Metric: MetricCIEDE2000, Tolerance: 2}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, ok := Find(hay, needle, opt); !ok {
+			b.Fatal("not found")
+		}
+	}
+}
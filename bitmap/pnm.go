@@ -0,0 +1,294 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// The MMIOErrorString errors of the ppm, pgm and pam images
+var (
+	ErrPPMHeader     = errors.New("Not a PPM file")
+	ErrPPMColorDepth = errors.New("Unsupported color depth in PPM file")
+	ErrPPMPixelData  = errors.New("Could not read PPM pixel data")
+)
+
+// maxPNMPixels is the max width * height of the pnm images,
+// the larger header is rejected before the pixels are allocated
+const maxPNMPixels = 1 << 26
+
+func init() {
+	image.RegisterFormat("pgm", "P5", decodePNM, decodePNMConfig)
+	image.RegisterFormat("ppm", "P6", decodePNM, decodePNMConfig)
+	image.RegisterFormat("pam", "P7", decodePNM, decodePNMConfig)
+}
+
+// pnmHeader is the header of the binary pgm (P5), ppm (P6) and pam (P7)
+type pnmHeader struct {
+	width, height int
+	// depth the samples of a pixel: gray, gray alpha, rgb or rgb alpha
+	depth  int
+	maxval int
+}
+
+// pnmToken read the next header token, the comments are skipped;
+// the single whitespace after the token is read too
+func pnmToken(r *bufio.Reader) (string, error) {
+	var tok []byte
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return "", ErrPPMHeader
+		}
+
+		switch {
+		case c == '#' && len(tok) == 0:
+			if _, err := r.ReadString('\n'); err != nil {
+				return "", ErrPPMHeader
+			}
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if len(tok) > 0 {
+				return string(tok), nil
+			}
+		default:
+			tok = append(tok, c)
+		}
+	}
+}
+
+// readPNMHeader read the header, the reader is at the pixels on return
+func readPNMHeader(r *bufio.Reader) (pnmHeader, error) {
+	var h pnmHeader
+
+	magic, err := pnmToken(r)
+	if err != nil {
+		return h, err
+	}
+
+	switch magic {
+	case "P5", "P6":
+		var v [3]int
+		for i := range v {
+			tok, err := pnmToken(r)
+			if err != nil {
+				return h, err
+			}
+			if v[i], err = strconv.Atoi(tok); err != nil {
+				return h, ErrPPMHeader
+			}
+		}
+
+		h = pnmHeader{width: v[0], height: v[1], depth: 1, maxval: v[2]}
+		if magic == "P6" {
+			h.depth = 3
+		}
+	case "P7":
+		if h, err = readPAMHeader(r); err != nil {
+			return h, err
+		}
+	default:
+		return h, ErrPPMHeader
+	}
+
+	if h.width <= 0 || h.height <= 0 || h.width > maxPNMPixels/h.height {
+		return h, ErrPPMHeader
+	}
+	if h.depth < 1 || h.depth > 4 || h.maxval < 1 || h.maxval > 0xffff {
+		return h, ErrPPMColorDepth
+	}
+
+	return h, nil
+}
+
+// readPAMHeader read the "KEY value" lines of the pam until ENDHDR
+func readPAMHeader(r *bufio.Reader) (pnmHeader, error) {
+	var h pnmHeader
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return h, ErrPPMHeader
+		}
+
+		f := strings.Fields(line)
+		if len(f) == 0 || strings.HasPrefix(f[0], "#") {
+			continue
+		}
+		if f[0] == "ENDHDR" {
+			return h, nil
+		}
+		if len(f) < 2 {
+			return h, ErrPPMHeader
+		}
+
+		var p *int
+		switch f[0] {
+		case "WIDTH":
+			p = &h.width
+		case "HEIGHT":
+			p = &h.height
+		case "DEPTH":
+			p = &h.depth
+		case "MAXVAL":
+			p = &h.maxval
+		default:
+			// TUPLTYPE, the depth is enough
+			continue
+		}
+
+		if *p, err = strconv.Atoi(f[1]); err != nil {
+			return h, ErrPPMHeader
+		}
+	}
+}
+
+func (h pnmHeader) colorModel() color.Model {
+	switch h.depth {
+	case 1:
+		return color.GrayModel
+	case 3:
+		return color.RGBAModel
+	}
+
+	return color.NRGBAModel
+}
+
+func decodePNMConfig(r io.Reader) (image.Config, error) {
+	h, err := readPNMHeader(bufio.NewReader(r))
+	if err != nil {
+		return image.Config{}, err
+	}
+
+	return image.Config{ColorModel: h.colorModel(),
+		Width: h.width, Height: h.height}, nil
+}
+
+// decodePNM decode the binary pgm, ppm and pam, the samples
+// of the other maxval (e.g. 16 bit) are scaled to 8 bit
+func decodePNM(r io.Reader) (image.Image, error) {
+	br := bufio.NewReader(r)
+	h, err := readPNMHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	size := 1
+	if h.maxval > 0xff {
+		size = 2
+	}
+	sample := func(b []byte) uint8 {
+		v := int(b[0])
+		if size == 2 {
+			v = v<<8 | int(b[1])
+		}
+		if h.maxval != 0xff {
+			v = (v*0xff + h.maxval/2) / h.maxval
+		}
+		if v > 0xff {
+			v = 0xff
+		}
+		return uint8(v)
+	}
+
+	// read the pixels first, the short input of a large
+	// header fails before the image is allocated
+	n := h.width * h.depth * size
+	data, err := ioutil.ReadAll(io.LimitReader(br, int64(n*h.height)))
+	if err != nil || len(data) < n*h.height {
+		return nil, ErrPPMPixelData
+	}
+
+	rect := image.Rect(0, 0, h.width, h.height)
+	var (
+		pix    []uint8
+		stride int
+		img    image.Image
+	)
+	switch h.depth {
+	case 1:
+		m := image.NewGray(rect)
+		pix, stride, img = m.Pix, m.Stride, m
+	case 3:
+		m := image.NewRGBA(rect)
+		pix, stride, img = m.Pix, m.Stride, m
+	default:
+		m := image.NewNRGBA(rect)
+		pix, stride, img = m.Pix, m.Stride, m
+	}
+
+	for y := 0; y < h.height; y++ {
+		row := data[y*n:]
+		dst := pix[y*stride:]
+		for x := 0; x < h.width; x++ {
+			src := row[x*h.depth*size:]
+			switch h.depth {
+			case 1:
+				dst[x] = sample(src)
+			case 2:
+				g := sample(src)
+				dst[x*4], dst[x*4+1], dst[x*4+2] = g, g, g
+				dst[x*4+3] = sample(src[size:])
+			default:
+				for i := 0; i < h.depth; i++ {
+					dst[x*4+i] = sample(src[i*size:])
+				}
+				if h.depth == 3 {
+					dst[x*4+3] = 0xff
+				}
+			}
+		}
+	}
+
+	return img, nil
+}
+
+// encodePNM write the image as the binary ppm (P6), the alpha is
+// dropped; or as the pam (P7), RGB_ALPHA if the image is not opaque
+func encodePNM(w io.Writer, img *image.NRGBA, pam bool) error {
+	var (
+		b     = img.Rect
+		depth = 3
+		bw    = bufio.NewWriter(w)
+	)
+
+	if pam {
+		tuple := "RGB"
+		if !img.Opaque() {
+			depth, tuple = 4, "RGB_ALPHA"
+		}
+		fmt.Fprintf(bw, "P7\nWIDTH %d\nHEIGHT %d\nDEPTH %d\nMAXVAL 255\n"+
+			"TUPLTYPE %s\nENDHDR\n", b.Dx(), b.Dy(), depth, tuple)
+	} else {
+		fmt.Fprintf(bw, "P6\n%d %d\n255\n", b.Dx(), b.Dy())
+	}
+
+	row := make([]byte, b.Dx()*depth)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		src := img.Pix[img.PixOffset(b.Min.X, y):]
+		for x := 0; x < b.Dx(); x++ {
+			copy(row[x*depth:x*depth+depth], src[x*4:])
+		}
+
+		if _, err := bw.Write(row); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,404 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+const (
+	// minPyramidNeedle is the min needle width and height
+	// of the coarse level, the levels are reduced to keep it
+	minPyramidNeedle = 4
+	// defaultCandidates is the count of the coarse candidates
+	defaultCandidates = 16
+	// pyramidSlack is the coarse score margin of FindAll, the coarse
+	// needle is not aligned to the match (less than 1 coarse pixel)
+	pyramidSlack = 0.1
+)
+
+// shrink downsample the bitmap by f with the area (box) filter
+func shrink(bit *Bitmap, f int) *Bitmap {
+	w, h := bit.Width/f, bit.Height/f
+	dst := New(w, h)
+	n := f * f
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var r, g, b int
+			for j := 0; j < f; j++ {
+				for i := 0; i < f; i++ {
+					cr, cg, cb := bit.RGBAt(x*f+i, y*f+j)
+					r += int(cr)
+					g += int(cg)
+					b += int(cb)
+				}
+			}
+
+			dst.SetRGB(x, y, uint8((r+n/2)/n), uint8((g+n/2)/n),
+				uint8((b+n/2)/n))
+		}
+	}
+
+	return dst
+}
+
+// shrinkMask downsample the mask by f, a coarse pixel is
+// compared only if all of its pixels are compared
+func shrinkMask(m []bool, w, h, f int) []bool {
+	if m == nil {
+		return nil
+	}
+
+	cw, ch := w/f, h/f
+	dst := make([]bool, cw*ch)
+	for y := 0; y < ch; y++ {
+		for x := 0; x < cw; x++ {
+			v := true
+			for j := 0; j < f && v; j++ {
+				for i := 0; i < f; i++ {
+					if !m[(y*f+j)*w+x*f+i] {
+						v = false
+						break
+					}
+				}
+			}
+			dst[y*cw+x] = v
+		}
+	}
+
+	return dst
+}
+
+// coarse make the coarse searcher of s on the shrunk haystack,
+// return nil if the needle is too small for the levels
+func (s *searcher) coarse(hay *Bitmap, f int) *searcher {
+	nw, nh := s.needle.Width/f, s.needle.Height/f
+	if nw < minPyramidNeedle || nh < minPyramidNeedle {
+		return nil
+	}
+
+	cs := &searcher{
+		hay:    hay,
+		needle: shrink(s.needle, f),
+		origin: s.origin,
+		scale:  s.scale,
+		mask:   shrinkMask(s.mask, s.needle.Width, s.needle.Height, f),
+		method: MatchSSD,
+		minNCC: -1,
+	}
+
+	cs.count = nw * nh
+	for _, v := range cs.mask {
+		if !v {
+			cs.count--
+		}
+	}
+	if cs.count == 0 {
+		return nil
+	}
+
+	if s.method == MatchNCC {
+		cs.method = MatchNCC
+		cs.prepareNCC()
+	}
+
+	cs.rect = image.Rect(s.rect.Min.X/f, s.rect.Min.Y/f,
+		(s.rect.Max.X-1)/f+1, (s.rect.Max.Y-1)/f+1).
+		Intersect(image.Rect(0, 0, hay.Width-nw+1, hay.Height-nh+1))
+	if cs.rect.Empty() {
+		return nil
+	}
+
+	return cs
+}
+
+// score compute the coarse score at (x, y), stop early and
+// return false if the score is not better than bound
+func (s *searcher) score(x, y int, bound float64) (float64, bool) {
+	if s.method == MatchNCC {
+		score, _ := s.matchNCC(x, y)
+		return score, score > bound
+	}
+
+	var (
+		hay    = s.hay
+		needle = s.needle
+		hbpp   = int(hay.BytesPerPixel)
+		nbpp   = int(needle.BytesPerPixel)
+		sum    float64
+		max    = math.Inf(1)
+	)
+
+	if bound > -1 {
+		d := (1 - bound) * maxDistance
+		max = d * d * float64(s.count)
+	}
+
+	for ny := 0; ny < needle.Height; ny++ {
+		hrow := hay.ImageBuffer[(y+ny)*hay.Bytewidth+x*hbpp:]
+		nrow := needle.ImageBuffer[ny*needle.Bytewidth:]
+
+		for nx := 0; nx < needle.Width; nx++ {
+			if s.mask != nil && !s.mask[ny*needle.Width+nx] {
+				continue
+			}
+
+			hi, ni := nx*hbpp, nx*nbpp
+			db := int(hrow[hi]) - int(nrow[ni])
+			dg := int(hrow[hi+1]) - int(nrow[ni+1])
+			dr := int(hrow[hi+2]) - int(nrow[ni+2])
+
+			sum += float64(dr*dr + dg*dg + db*db)
+		}
+
+		if sum >= max {
+			return 0, false
+		}
+	}
+
+	return 1 - math.Sqrt(sum/float64(s.count))/maxDistance, true
+}
+
+// topK keeps the k best candidates, the neighbor candidates
+// (1 pixel) are suppressed by the better one
+type topK struct {
+	k    int
+	list []Result
+}
+
+// bound returns the score a new candidate must beat
+func (t *topK) bound() float64 {
+	if len(t.list) < t.k {
+		return -2
+	}
+
+	return t.list[len(t.list)-1].Score
+}
+
+func (t *topK) add(r Result) {
+	for i, o := range t.list {
+		if abs(o.X-r.X) <= 1 && abs(o.Y-r.Y) <= 1 {
+			if !better(r, o) {
+				return
+			}
+			t.list = append(t.list[:i], t.list[i+1:]...)
+			break
+		}
+	}
+
+	i := sort.Search(len(t.list), func(i int) bool {
+		return better(r, t.list[i])
+	})
+	t.list = append(t.list, Result{})
+	copy(t.list[i+1:], t.list[i:])
+	t.list[i] = r
+
+	if len(t.list) > t.k {
+		t.list = t.list[:t.k]
+	}
+}
+
+// candidates returns the k best points of the coarse searcher,
+// and every point of the score >= min if min > -1
+func (s *searcher) candidates(k int, min float64, workers int) []Result {
+	tiles := makeTiles([]*searcher{s}, workers)
+	out := make([]topK, len(tiles))
+	above := make([][]Result, len(tiles))
+
+	runTiles(len(tiles), workers, func(i int) {
+		t := tiles[i]
+		top := &out[i]
+		top.k = k
+
+		for y := t.y0; y < t.y1; y++ {
+			for x := s.rect.Min.X; x < s.rect.Max.X; x++ {
+				bound := top.bound()
+				if min > -1 && min-eps < bound {
+					bound = min - eps
+				}
+
+				score, ok := s.score(x, y, bound)
+				if !ok {
+					continue
+				}
+
+				r := Result{X: x, Y: y, Score: score}
+				if min > -1 && score >= min-eps {
+					above[i] = append(above[i], r)
+				}
+				if score > top.bound() {
+					top.add(r)
+				}
+			}
+		}
+	})
+
+	all := &topK{k: k}
+	for _, t := range out {
+		for _, r := range t.list {
+			all.add(r)
+		}
+	}
+
+	res := all.list
+	for _, a := range above {
+		res = append(res, a...)
+	}
+
+	return res
+}
+
+// minScore returns the min coarse (RGB) score of a match, false if
+// the metric has no RGB bound, e.g. a small ΔE may be a large RGB distance
+func (s *searcher) minScore() (float64, bool) {
+	switch {
+	case s.method == MatchNCC:
+		return s.minNCC, true
+	case s.cmp == nil:
+		return 1 - math.Sqrt(s.limit)/maxDistance, true
+	case s.cmp.metric == MetricChannel:
+		// the channels within tol are within tol * sqrt(3)
+		return 1 - s.cmp.tol*math.Sqrt(3)/maxDistance, true
+	}
+
+	return 0, false
+}
+
+// refine search the candidates of the coarse level in the
+// f x f windows of the full resolution, return the best match of
+// every window
+func (s *searcher) refine(cands []Result, f int) []Result {
+	var res []Result
+
+	for _, c := range cands {
+		win := image.Rect(c.X*f-f, c.Y*f-f, c.X*f+f+1, c.Y*f+f+1).
+			Intersect(s.rect)
+
+		var (
+			best  Result
+			found bool
+		)
+		for y := win.Min.Y; y < win.Max.Y; y++ {
+			for x := win.Min.X; x < win.Max.X; x++ {
+				score, ok := s.match(x, y)
+				if !ok {
+					continue
+				}
+
+				r := s.result(x, y, score)
+				if !found || better(r, best) {
+					best, found = r, true
+				}
+			}
+		}
+
+		if found {
+			res = append(res, best)
+		}
+	}
+
+	return res
+}
+
+// refineAll search the f x f windows of the candidates of the coarse
+// level in the full resolution, return every match in rows order
+func (s *searcher) refineAll(cands []Result, f, workers int) []Result {
+	r := s.rect
+	marked := make([]bool, r.Dx()*r.Dy())
+	for _, c := range cands {
+		win := image.Rect(c.X*f-f, c.Y*f-f, c.X*f+f+1, c.Y*f+f+1).Intersect(r)
+		for y := win.Min.Y; y < win.Max.Y; y++ {
+			row := marked[(y-r.Min.Y)*r.Dx():]
+			for x := win.Min.X; x < win.Max.X; x++ {
+				row[x-r.Min.X] = true
+			}
+		}
+	}
+
+	tiles := makeTiles([]*searcher{s}, workers)
+	out := make([][]Result, len(tiles))
+	runTiles(len(tiles), workers, func(i int) {
+		for y := tiles[i].y0; y < tiles[i].y1; y++ {
+			row := marked[(y-r.Min.Y)*r.Dx():]
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if !row[x-r.Min.X] {
+					continue
+				}
+				if score, ok := s.match(x, y); ok {
+					out[i] = append(out[i], s.result(x, y, score))
+				}
+			}
+		}
+	})
+
+	return merge(out, scanAll)
+}
+
+// scanPyramid coarse to fine search of the searchers, return the
+// refined matches; all returns every match of the coarse points within
+// the tolerance, instead of the best of the k best coarse points;
+// the searchers with a too small needle are searched in the full resolution
+func scanPyramid(list []*searcher, opt *Options, all bool) []Result {
+	var (
+		res    []Result
+		shrunk = map[int]*Bitmap{}
+	)
+
+	for _, s := range list {
+		var (
+			cs *searcher
+			f  int
+		)
+
+		min, ok := s.minScore()
+		if !ok {
+			res = append(res, scanTiles([]*searcher{s}, opt.Workers, scanAll)...)
+			continue
+		}
+
+		for l := opt.Pyramid; l > 0 && cs == nil; l-- {
+			f = 1 << uint(l)
+			hay, ok := shrunk[f]
+			if !ok {
+				hay = shrink(s.hay, f)
+				shrunk[f] = hay
+			}
+			cs = s.coarse(hay, f)
+		}
+
+		if cs == nil {
+			res = append(res, scanTiles([]*searcher{s}, opt.Workers, scanAll)...)
+			continue
+		}
+
+		if !all {
+			cands := cs.candidates(defaultCandidates, -2, opt.Workers)
+			res = append(res, s.refine(cands, f)...)
+			continue
+		}
+
+		cands := cs.candidates(defaultCandidates, min-pyramidSlack, opt.Workers)
+		res = append(res, s.refineAll(cands, f, opt.Workers)...)
+	}
+
+	return res
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
@@ -0,0 +1,74 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+// resample resize the bitmap to w x h with the bilinear filter
+func resample(bit *Bitmap, w, h int) *Bitmap {
+	dst := New(w, h)
+	sx := float64(bit.Width) / float64(w)
+	sy := float64(bit.Height) / float64(h)
+
+	for y := 0; y < h; y++ {
+		fy := (float64(y)+0.5)*sy - 0.5
+		y0, wy := split(fy, bit.Height)
+		y1 := clamp(y0+1, bit.Height)
+
+		for x := 0; x < w; x++ {
+			fx := (float64(x)+0.5)*sx - 0.5
+			x0, wx := split(fx, bit.Width)
+			x1 := clamp(x0+1, bit.Width)
+
+			r00, g00, b00 := bit.RGBAt(x0, y0)
+			r10, g10, b10 := bit.RGBAt(x1, y0)
+			r01, g01, b01 := bit.RGBAt(x0, y1)
+			r11, g11, b11 := bit.RGBAt(x1, y1)
+
+			dst.SetRGB(x, y,
+				lerp2(r00, r10, r01, r11, wx, wy),
+				lerp2(g00, g10, g01, g11, wx, wy),
+				lerp2(b00, b10, b01, b11, wx, wy))
+		}
+	}
+
+	return dst
+}
+
+// split returns the integer part (clamped to [0, n)) and the weight
+func split(f float64, n int) (int, float64) {
+	if f <= 0 {
+		return 0, 0
+	}
+
+	i := int(f)
+	if i >= n-1 {
+		return n - 1, 0
+	}
+
+	return i, f - float64(i)
+}
+
+func clamp(i, n int) int {
+	if i < 0 {
+		return 0
+	}
+	if i >= n {
+		return n - 1
+	}
+
+	return i
+}
+
+func lerp2(c00, c10, c01, c11 uint8, wx, wy float64) uint8 {
+	top := float64(c00)*(1-wx) + float64(c10)*wx
+	bottom := float64(c01)*(1-wx) + float64(c11)*wx
+
+	return uint8(top*(1-wy) + bottom*wy + 0.5)
+}
@@ -0,0 +1,181 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+)
+
+// Offset is the color of the pixel at (X, Y) of the anchor
+type Offset struct {
+	X, Y  int
+	Color uint32
+}
+
+// Signature is a multi-point color pattern, the anchor color
+// and the colors at the offsets around the anchor
+type Signature struct {
+	// Color the 0xRRGGBB anchor color
+	Color   uint32
+	Offsets []Offset
+}
+
+// SignatureAt make the signature of the image pixels, the anchor at p
+// and the offsets of p; return false if a pixel is out of the image
+func SignatureAt(img image.Image, p image.Point, offsets ...image.Point) (Signature, bool) {
+	bit := FromImage(img)
+	if bit == nil {
+		return Signature{}, false
+	}
+
+	p = p.Sub(img.Bounds().Min)
+	if !bit.InBounds(p.X, p.Y) {
+		return Signature{}, false
+	}
+
+	sig := Signature{Color: bit.HexAt(p.X, p.Y)}
+	for _, o := range offsets {
+		x, y := p.X+o.X, p.Y+o.Y
+		if !bit.InBounds(x, y) {
+			return Signature{}, false
+		}
+
+		sig.Offsets = append(sig.Offsets, Offset{X: o.X, Y: o.Y, Color: bit.HexAt(x, y)})
+	}
+
+	return sig, true
+}
+
+// Bounds returns the rect of the signature pixels, relative to the anchor
+func (sig Signature) Bounds() image.Rectangle {
+	r := image.Rect(0, 0, 1, 1)
+	for _, o := range sig.Offsets {
+		r = r.Union(image.Rect(o.X, o.Y, o.X+1, o.Y+1))
+	}
+
+	return r
+}
+
+// sigMatcher is the prepared signature of one search
+type sigMatcher struct {
+	bit     *Bitmap
+	anchor  func(c uint32) bool
+	offsets []func(c uint32) bool
+	sig     Signature
+}
+
+func newSigMatcher(bit *Bitmap, sig Signature, opt *ColorOptions) *sigMatcher {
+	m := &sigMatcher{bit: bit, anchor: opt.matcher(sig.Color), sig: sig}
+	for _, o := range sig.Offsets {
+		m.offsets = append(m.offsets, opt.matcher(o.Color))
+	}
+
+	return m
+}
+
+// match whether the signature matches at the anchor (x, y),
+// all signature pixels must be in the bitmap
+func (m *sigMatcher) match(x, y int) bool {
+	if !m.anchor(m.bit.HexAt(x, y)) {
+		return false
+	}
+
+	for i, o := range m.sig.Offsets {
+		if !m.offsets[i](m.bit.HexAt(x+o.X, y+o.Y)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// anchors returns the rect of the anchor points, the whole
+// signature is in the search rect
+func (m *sigMatcher) anchors(origin image.Point, rect image.Rectangle) image.Rectangle {
+	area := m.bit.Bounds()
+	if !rect.Empty() {
+		area = area.Intersect(rect.Sub(origin))
+	}
+
+	b := m.sig.Bounds()
+	return image.Rect(area.Min.X-b.Min.X, area.Min.Y-b.Min.Y,
+		area.Max.X-b.Max.X+1, area.Max.Y-b.Max.Y+1)
+}
+
+// MatchSignature whether the signature matches at the anchor point p
+func MatchSignature(img image.Image, sig Signature, p image.Point,
+	opt *ColorOptions) bool {
+	bit := FromImage(img)
+	if bit == nil {
+		return false
+	}
+	if opt == nil {
+		opt = &ColorOptions{}
+	}
+
+	p = p.Sub(img.Bounds().Min)
+	if !sig.Bounds().Add(p).In(bit.Bounds()) {
+		return false
+	}
+
+	return newSigMatcher(bit, sig, opt).match(p.X, p.Y)
+}
+
+// FindSignature find the first anchor point of the signature,
+// in rows order
+func FindSignature(img image.Image, sig Signature, opt *ColorOptions) (image.Point, bool) {
+	all := findSignature(img, sig, opt, 1)
+	if len(all) == 0 {
+		return image.Point{}, false
+	}
+
+	return all[0], true
+}
+
+// FindAllSignature returns every anchor point of the signature,
+// in rows order; opt.Rect limits the whole signature
+func FindAllSignature(img image.Image, sig Signature, opt *ColorOptions) []image.Point {
+	return findSignature(img, sig, opt, 0)
+}
+
+// findSignature find max anchor points, 0 is unlimited
+func findSignature(img image.Image, sig Signature, opt *ColorOptions,
+	max int) []image.Point {
+	bit := FromImage(img)
+	if bit == nil {
+		return nil
+	}
+	if opt == nil {
+		opt = &ColorOptions{}
+	}
+
+	var (
+		points []image.Point
+		origin = img.Bounds().Min
+		m      = newSigMatcher(bit, sig, opt)
+		area   = m.anchors(origin, opt.Rect)
+	)
+
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			if !m.match(x, y) {
+				continue
+			}
+
+			points = append(points, image.Pt(x, y).Add(origin))
+			if max > 0 && len(points) >= max {
+				return points
+			}
+		}
+	}
+
+	return points
+}
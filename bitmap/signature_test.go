@@ -0,0 +1,94 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+	"testing"
+)
+
+// checkbox draw a 5x5 checkbox at (x, y), clipped by the bitmap
+func checkbox(bit *Bitmap, x, y int, check uint32) {
+	fill(bit, image.Rect(x, y, x+5, y+5).Intersect(bit.Bounds()), 0x333333)
+	fill(bit, image.Rect(x+1, y+1, x+4, y+4).Intersect(bit.Bounds()), check)
+}
+
+func TestFindSignature(t *testing.T) {
+	bit := New(30, 20)
+	fill(bit, bit.Bounds(), 0xffffff)
+	checkbox(bit, 2, 3, 0x00aa00)
+	checkbox(bit, 20, 3, 0xffffff)
+	checkbox(bit, 24, 14, 0x00ab00)
+	checkbox(bit, 0, 16, 0x00aa00) // partly out of the bitmap
+
+	// the border corner, the check mark and the border outside
+	sig, ok := SignatureAt(bit, image.Pt(2, 3), image.Pt(2, 2), image.Pt(4, 4),
+		image.Pt(-1, -1))
+	if !ok || sig.Color != 0x333333 || len(sig.Offsets) != 3 ||
+		sig.Offsets[0].Color != 0x00aa00 || sig.Offsets[2].Color != 0xffffff {
+		t.Fatalf("SignatureAt got %+v", sig)
+	}
+	if b := sig.Bounds(); b != image.Rect(-1, -1, 5, 5) {
+		t.Errorf("Bounds got %v", b)
+	}
+
+	p, ok := FindSignature(bit, sig, nil)
+	if !ok || p != image.Pt(2, 3) {
+		t.Errorf("FindSignature got %v %v", p, ok)
+	}
+
+	all := FindAllSignature(bit, sig, &ColorOptions{Tolerance: 0.01})
+	if len(all) != 2 || all[0] != image.Pt(2, 3) || all[1] != image.Pt(24, 14) {
+		t.Errorf("FindAllSignature got %v", all)
+	}
+
+	// the signature must be in the rect
+	all = FindAllSignature(bit, sig, &ColorOptions{Tolerance: 0.01,
+		Rect: image.Rect(2, 2, 30, 20)})
+	if len(all) != 1 || all[0] != image.Pt(24, 14) {
+		t.Errorf("FindAllSignature in rect got %v", all)
+	}
+
+	if !MatchSignature(bit, sig, image.Pt(2, 3), nil) ||
+		MatchSignature(bit, sig, image.Pt(20, 3), nil) ||
+		MatchSignature(bit, sig, image.Pt(0, 16), nil) {
+		t.Error("MatchSignature")
+	}
+
+	// the points are in the image coordinates
+	sub := bit.ToRGBA().SubImage(image.Rect(15, 10, 30, 20))
+	if p, ok := FindSignature(sub, sig, &ColorOptions{Tolerance: 0.01}); !ok ||
+		p != image.Pt(24, 14) {
+		t.Errorf("FindSignature of sub image got %v %v", p, ok)
+	}
+	if s, ok := SignatureAt(sub, image.Pt(24, 14), image.Pt(2, 2)); !ok ||
+		s.Color != 0x333333 || s.Offsets[0].Color != 0x00ab00 {
+		t.Errorf("SignatureAt of sub image got %+v %v", s, ok)
+	}
+
+	// every pixel must be in the image
+	for _, c := range []struct {
+		img     image.Image
+		p       image.Point
+		offsets []image.Point
+	}{
+		{nil, image.Pt(0, 0), nil},
+		{bit, image.Pt(30, 0), nil},
+		{bit, image.Pt(-1, 0), nil},
+		{bit, image.Pt(28, 0), []image.Point{{2, 0}}},
+		{bit, image.Pt(2, 2), []image.Point{{0, 18}}},
+		{sub, image.Pt(15, 10), []image.Point{{-1, 0}}},
+	} {
+		if _, ok := SignatureAt(c.img, c.p, c.offsets...); ok {
+			t.Errorf("SignatureAt %v %v out of the image", c.p, c.offsets)
+		}
+	}
+}
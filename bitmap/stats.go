@@ -0,0 +1,245 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+	"sort"
+)
+
+// Histogram is the per channel histogram of an image
+type Histogram struct {
+	R, G, B [256]int
+	// Y the luminance, as color.GrayModel
+	Y [256]int
+	// Count the count of the pixels
+	Count int
+}
+
+// NewHistogram returns the histogram of the image,
+// nil if the image is nil
+func NewHistogram(img image.Image) *Histogram {
+	bit := FromImage(img)
+	if bit == nil {
+		return nil
+	}
+
+	h := &Histogram{Count: bit.Width * bit.Height}
+	bpp := int(bit.BytesPerPixel)
+	for y := 0; y < bit.Height; y++ {
+		row := bit.ImageBuffer[y*bit.Bytewidth:]
+		for x := 0; x < bit.Width*bpp; x += bpp {
+			h.B[row[x]]++
+			h.G[row[x+1]]++
+			h.R[row[x+2]]++
+			h.Y[luma(row[x:])]++
+		}
+	}
+
+	return h
+}
+
+// rgb returns the 0xRRGGBB color of the channel values
+func rgb(fn func(ch *[256]int) uint8, h *Histogram) uint32 {
+	return uint32(fn(&h.R))<<16 | uint32(fn(&h.G))<<8 | uint32(fn(&h.B))
+}
+
+// Mean returns the mean 0xRRGGBB color, 0 if there is no pixel
+func (h *Histogram) Mean() uint32 {
+	if h.Count == 0 {
+		return 0
+	}
+
+	return rgb(func(ch *[256]int) uint8 {
+		sum := 0
+		for v, n := range ch {
+			sum += v * n
+		}
+		return uint8((sum + h.Count/2) / h.Count)
+	}, h)
+}
+
+// Median returns the per channel median 0xRRGGBB color,
+// 0 if there is no pixel
+func (h *Histogram) Median() uint32 {
+	if h.Count == 0 {
+		return 0
+	}
+
+	return rgb(func(ch *[256]int) uint8 {
+		sum := 0
+		for v, n := range ch {
+			if sum += n; 2*sum >= h.Count {
+				return uint8(v)
+			}
+		}
+		return 0xff
+	}, h)
+}
+
+// DominantColor is a color cluster of the image
+type DominantColor struct {
+	// Color the mean 0xRRGGBB color of the cluster
+	Color uint32
+	// Count the count of the pixels
+	Count int
+	// Ratio the part of the image 0.0 - 1.0
+	Ratio float64
+}
+
+// colorCount is a distinct color of the image
+type colorCount struct {
+	c [3]uint8 // r, g, b
+	n int
+}
+
+// colorBox is a median cut box of the colors
+type colorBox struct {
+	colors []colorCount
+	count  int
+}
+
+// variance returns the channel of the largest variance and the
+// sum of the squared errors of the box
+func (b *colorBox) variance() (int, float64) {
+	var sum, sq [3]float64
+	for _, c := range b.colors {
+		for i, v := range c.c {
+			sum[i] += float64(v) * float64(c.n)
+			sq[i] += float64(v) * float64(v) * float64(c.n)
+		}
+	}
+
+	ch, sse, max := 0, 0.0, -1.0
+	for i := range sum {
+		e := sq[i] - sum[i]*sum[i]/float64(b.count)
+		if e > max {
+			ch, max = i, e
+		}
+		sse += e
+	}
+
+	return ch, sse
+}
+
+// split split the box on the channel where the between class
+// variance is the largest, as otsu
+func (b *colorBox) split(ch int) (colorBox, colorBox) {
+	sort.SliceStable(b.colors, func(i, j int) bool {
+		return b.colors[i].c[ch] < b.colors[j].c[ch]
+	})
+
+	var total float64
+	for _, c := range b.colors {
+		total += float64(c.c[ch]) * float64(c.n)
+	}
+
+	// both boxes have a color
+	cut, best, n, sum := 1, -1.0, 0, 0.0
+	for i := 0; i < len(b.colors)-1; i++ {
+		n += b.colors[i].n
+		sum += float64(b.colors[i].c[ch]) * float64(b.colors[i].n)
+		if b.colors[i].c[ch] == b.colors[i+1].c[ch] {
+			continue
+		}
+
+		wl, wr := float64(n), float64(b.count-n)
+		d := sum/wl - (total-sum)/wr
+		if v := wl * wr * d * d; v > best {
+			cut, best = i+1, v
+		}
+	}
+
+	l, r := colorBox{colors: b.colors[:cut]}, colorBox{colors: b.colors[cut:]}
+	for _, c := range l.colors {
+		l.count += c.n
+	}
+	r.count = b.count - l.count
+
+	return l, r
+}
+
+// mean returns the mean color of the box
+func (b *colorBox) mean() uint32 {
+	var s [3]int
+	for _, c := range b.colors {
+		for i, v := range c.c {
+			s[i] += int(v) * c.n
+		}
+	}
+
+	var c uint32
+	for _, v := range s {
+		c = c<<8 | uint32((v+b.count/2)/b.count)
+	}
+
+	return c
+}
+
+// DominantColors returns at most n dominant colors of the image by the
+// median cut, the box of the largest squared error is cut at the best
+// variance split; the largest first, e.g. the panel is mostly red if
+// the first color is red and its Ratio > 0.5
+func DominantColors(img image.Image, n int) []DominantColor {
+	bit := FromImage(img)
+	if bit == nil || n <= 0 || bit.Width == 0 || bit.Height == 0 {
+		return nil
+	}
+
+	counts := make(map[uint32]int)
+	bpp := int(bit.BytesPerPixel)
+	for y := 0; y < bit.Height; y++ {
+		row := bit.ImageBuffer[y*bit.Bytewidth:]
+		for x := 0; x < bit.Width*bpp; x += bpp {
+			counts[uint32(row[x+2])<<16|uint32(row[x+1])<<8|uint32(row[x])]++
+		}
+	}
+
+	// the map order is random, the cuts are not
+	keys := make([]int, 0, len(counts))
+	for c := range counts {
+		keys = append(keys, int(c))
+	}
+	sort.Ints(keys)
+
+	all := colorBox{count: bit.Width * bit.Height}
+	for _, c := range keys {
+		all.colors = append(all.colors, colorCount{
+			c: [3]uint8{uint8(c >> 16), uint8(c >> 8), uint8(c)}, n: counts[uint32(c)]})
+	}
+
+	boxes := []colorBox{all}
+	for len(boxes) < n {
+		// cut the box of the largest squared error
+		best, bestCh, bestErr := -1, 0, 0.5
+		for i := range boxes {
+			if ch, e := boxes[i].variance(); e > bestErr {
+				best, bestCh, bestErr = i, ch, e
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		l, r := boxes[best].split(bestCh)
+		boxes[best] = l
+		boxes = append(boxes, r)
+	}
+
+	res := make([]DominantColor, len(boxes))
+	for i := range boxes {
+		res[i] = DominantColor{Color: boxes[i].mean(), Count: boxes[i].count,
+			Ratio: float64(boxes[i].count) / float64(all.count)}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Count > res[j].Count })
+
+	return res
+}
@@ -0,0 +1,67 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+	"testing"
+)
+
+func TestHistogram(t *testing.T) {
+	bit := New(10, 10)
+	fill(bit, image.Rect(0, 0, 10, 6), 0xff0000)
+	fill(bit, image.Rect(0, 6, 10, 10), 0x0000ff)
+
+	h := NewHistogram(bit)
+	if h.Count != 100 || h.R[0xff] != 60 || h.B[0xff] != 40 || h.G[0] != 100 {
+		t.Fatalf("histogram got %d %d %d", h.Count, h.R[0xff], h.B[0xff])
+	}
+	if h.Y[76] != 60 || h.Y[29] != 40 {
+		t.Errorf("luminance got %d %d", h.Y[76], h.Y[29])
+	}
+	if m := h.Mean(); m != 0x990066 {
+		t.Errorf("Mean got %06x", m)
+	}
+	if m := h.Median(); m != 0xff0000 {
+		t.Errorf("Median got %06x", m)
+	}
+
+	if (&Histogram{}).Mean() != 0 || NewHistogram(nil) != nil {
+		t.Error("the empty histogram")
+	}
+}
+
+func TestDominantColors(t *testing.T) {
+	bit := New(10, 10)
+	fill(bit, bit.Bounds(), 0xe01010)
+	fill(bit, image.Rect(0, 6, 10, 9), 0x1010e0)
+	fill(bit, image.Rect(0, 9, 10, 10), 0xffffff)
+	// the noise of the red panel
+	bit.SetRGB(1, 1, 0xe2, 0x10, 0x10)
+	bit.SetRGB(2, 1, 0xde, 0x10, 0x10)
+
+	d := DominantColors(bit, 3)
+	if len(d) != 3 || d[0].Color != 0xe01010 || d[0].Count != 60 ||
+		d[0].Ratio != 0.6 || d[1].Color != 0x1010e0 || d[2].Color != 0xffffff {
+		t.Fatalf("DominantColors got %+v", d)
+	}
+
+	if d := DominantColors(bit, 1); len(d) != 1 || d[0].Count != 100 {
+		t.Errorf("one color got %+v", d)
+	}
+	flat := New(4, 4)
+	if d := DominantColors(flat, 5); len(d) != 1 || d[0].Color != 0 || d[0].Ratio != 1 {
+		t.Errorf("flat got %+v", d)
+	}
+	if DominantColors(bit, 0) != nil {
+		t.Error("n = 0")
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"errors"
+	"image"
+	"io"
+	"io/ioutil"
+	"strconv"
+)
+
+// The MMBMPStringError errors of the bitmap string; as the C
+// base64decode, the invalid chars are skipped and ErrStringDecode
+// is not returned by FromString
+var (
+	ErrStringHeader     = errors.New("Invalid header for string")
+	ErrStringDecode     = errors.New("Error decoding string")
+	ErrStringDecompress = errors.New("Error decompressing string")
+	ErrStringSize       = errors.New("String not of expected size")
+	ErrStringEncode     = errors.New("Error encoding string")
+	ErrStringCompress   = errors.New("Error compressing string")
+)
+
+// maxDimensionLen is the max digits of the width and height,
+// as MAX_DIMENSION_LEN
+const maxDimensionLen = 5
+
+// Compressor compress the raw data of the bitmap string to
+// a zlib stream, see ToString
+type Compressor func(raw []byte) ([]byte, error)
+
+// zlibCompress compress with compress/zlib, level 9 as zlib_compress
+func zlibCompress(raw []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	w, err := zlib.NewWriterLevel(&buf, zlib.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(raw); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ToString encode the image to the "b<width>,<height>,<data>" string of
+// createStringFromMMBitmap, the data is base64(zlib(24 bit BGR pixels));
+// compress is the zlib compressor, default compress/zlib level 9.
+//
+// The header and the pixels are the same as the C string, the deflate
+// stream of compress/zlib is not the same bytes as the C zlib (see
+// robotgo.TostringBitmap); the base64 is the standard one, the C
+// base64encode reads past the data, its last chars are not deterministic
+func ToString(img image.Image, compress ...Compressor) (string, error) {
+	bit := FromImage(img)
+	if bit == nil || bit.Width <= 0 || bit.Height <= 0 {
+		return "", ErrStringEncode
+	}
+
+	// a longer dimension can not be decoded
+	w, h := strconv.Itoa(bit.Width), strconv.Itoa(bit.Height)
+	if len(w) > maxDimensionLen || len(h) > maxDimensionLen {
+		return "", ErrStringEncode
+	}
+
+	comp := Compressor(zlibCompress)
+	if len(compress) > 0 && compress[0] != nil {
+		comp = compress[0]
+	}
+
+	data, err := comp(bit.rawBGR())
+	if err != nil || len(data) == 0 {
+		return "", ErrStringCompress
+	}
+
+	return "b" + w + "," + h + "," + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// rawBGR returns the 24 bit BGR pixels without the row padding
+func (bit *Bitmap) rawBGR() []byte {
+	raw := make([]byte, bit.Width*bit.Height*3)
+	bpp := int(bit.BytesPerPixel)
+
+	for y := 0; y < bit.Height; y++ {
+		row := bit.ImageBuffer[y*bit.Bytewidth:]
+		dst := raw[y*bit.Width*3:]
+		for x := 0; x < bit.Width; x++ {
+			copy(dst[x*3:x*3+3], row[x*bpp:x*bpp+3])
+		}
+	}
+
+	return raw
+}
+
+// FromString decode the "b<width>,<height>,<data>" string,
+// as createMMBitmapFromString; the bitmap is 24 bit
+func FromString(str string) (*Bitmap, error) {
+	if len(str) == 0 || str[0] != 'b' {
+		return nil, ErrStringHeader
+	}
+	str = str[1:]
+
+	w, n := parseDimension(str)
+	if w == 0 {
+		return nil, ErrStringHeader
+	}
+	pos := n + 1
+
+	var h int
+	if pos <= len(str) {
+		h, n = parseDimension(str[pos:])
+	}
+	if h == 0 {
+		return nil, ErrStringHeader
+	}
+	pos += n + 1
+
+	var data string
+	if pos < len(str) {
+		data = str[pos:]
+	}
+
+	r, err := zlib.NewReader(bytes.NewReader(decodeBase64(data)))
+	if err != nil {
+		return nil, ErrStringDecompress
+	}
+	defer r.Close()
+
+	// read one more byte to know the size does not match
+	size := w * h * 3
+	raw, err := ioutil.ReadAll(io.LimitReader(r, int64(size)+1))
+	if err != nil {
+		return nil, ErrStringDecompress
+	}
+	if len(raw) != size {
+		return nil, ErrStringSize
+	}
+
+	return FromBuffer(raw, w, h, w*3, 24), nil
+}
+
+// parseDimension parse the digits before the ',' (or '\0'),
+// return the dimension and the count of the digits, 0 on error;
+// as the C parseDimension, one more digit than maxDimensionLen is accepted
+func parseDimension(str string) (int, int) {
+	i := 0
+	for ; i < len(str) && str[i] != ',' && str[i] != 0; i++ {
+		if str[i] < '0' || str[i] > '9' || i > maxDimensionLen {
+			return 0, i
+		}
+	}
+
+	d, _ := strconv.Atoi(str[:i])
+	return d, i
+}
+
+// b64Decode is the base64decode table, -1 is not a base64 digit
+var b64Decode = func() (t [256]int8) {
+	const enc = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+	for i := range t {
+		t[i] = -1
+	}
+	for i := 0; i < len(enc); i++ {
+		t[enc[i]] = int8(i)
+	}
+
+	return
+}()
+
+// decodeBase64 decode as the C base64decode: the invalid chars
+// (e.g. '=') are skipped, but they count in the 4 chars blocks
+func decodeBase64(src string) []byte {
+	var (
+		last    int8
+		decoded = make([]byte, 0, (len(src)+3)/4*3)
+	)
+
+	for i := 0; i < len(src); i++ {
+		d := b64Decode[src[i]]
+		if d == -1 {
+			continue
+		}
+
+		switch i % 4 {
+		case 1:
+			decoded = append(decoded, byte(last<<2|(d&0x30)>>4))
+		case 2:
+			decoded = append(decoded, byte((last&0xf)<<4|(d&0x3c)>>2))
+		case 3:
+			decoded = append(decoded, byte((last&0x03)<<6|d))
+		}
+		last = d
+	}
+
+	return decoded
+}
@@ -0,0 +1,173 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// the strings of the C createStringFromMMBitmap, the bitmaps are
+// made by pattern with the rows padded by 2 bytes; the C base64encode
+// reads past the zlib data, the last chars are not deterministic
+var cStrings = []struct {
+	w, h, bpp, seed int
+	str             string
+}{
+	{1, 1, 24, 0, "b1,1,eNpjMM0CAADXAKA="},
+	{2, 1, 32, 1, "b2,1,eNpjyrnms00BAAf2Amc="},
+	{3, 2, 24, 2, "b3,2,eNpjW+pSKrL5SbOS+LbQNtUjX6cYAwBGWwgC"},
+	{5, 4, 32, 3, "b5,4,eNrjebBlQYmHCcedE3MKYgxYKnwUeJBEnuzoQRYJUPmBLAIAP8kb2Q=="},
+	{16, 9, 24, 4, "b16,9,eNoTkVU7e+1+W/8Me4/AH4xcG3cdziquUdYxvfP03dR5y/3CE9gFJA+" +
+		"cvFTZ1G1k7fL6y9/bT95OmbvMNyyejV9i/4mLFY1dhlbOrz7/Wbxma0xqnqic+rnrD9onzH" +
+		"TwDPrJxL1p95FFq7dEp+SKEG1LZlG1krYJ8bYYWDq9/PSbeFu+M3Bu2HmIeFv2Hb9Q3tBJvC" +
+		"2tfdPt3AOIt8UnNI6VT5x4WwBnQ9WZ"},
+	{7, 3, 32, 5, "b7,3,eNqTi5n1x6rilkTYjm8m064IlWz6EDThnEPdHjkM8aQF9zDFAReTIOk="},
+}
+
+func pattern(w, h, bpp, seed int) *Bitmap {
+	n := bpp / 8
+	bw := w*n + 2
+	buf := make([]uint8, bw*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			for c := 0; c < n; c++ {
+				buf[y*bw+x*n+c] = uint8((x*37 + y*91 + c*53 + seed) * (seed + 1))
+			}
+		}
+	}
+
+	return FromBuffer(buf, w, h, bw, uint8(bpp))
+}
+
+func samePixels(a, b *Bitmap) bool {
+	if a.Width != b.Width || a.Height != b.Height {
+		return false
+	}
+	for y := 0; y < a.Height; y++ {
+		for x := 0; x < a.Width; x++ {
+			if a.HexAt(x, y) != b.HexAt(x, y) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func TestFromString(t *testing.T) {
+	for _, c := range cStrings {
+		bit, err := FromString(c.str)
+		if err != nil {
+			t.Fatalf("%dx%d: %v", c.w, c.h, err)
+		}
+		if bit.BitsPerPixel != 24 || bit.Bytewidth != c.w*3 ||
+			!samePixels(bit, pattern(c.w, c.h, c.bpp, c.seed)) {
+			t.Errorf("%dx%d: the pixels are not the same", c.w, c.h)
+		}
+	}
+}
+
+// cZlib returns the zlib stream of the C string data, without the
+// bytes past the stream
+func cZlib(t *testing.T, data string) []byte {
+	z, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := bytes.NewReader(z)
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, zr); err != nil {
+		t.Fatal(err)
+	}
+
+	return z[:len(z)-r.Len()]
+}
+
+func TestToString(t *testing.T) {
+	for _, c := range cStrings {
+		bit := pattern(c.w, c.h, c.bpp, c.seed)
+
+		// the C zlib stream, the rest must be the same bytes up to
+		// the last full base64 block of the stream
+		i := strings.LastIndexByte(c.str, ',')
+		z := cZlib(t, c.str[i+1:])
+		str, err := ToString(bit, func(raw []byte) ([]byte, error) {
+			if len(raw) != c.w*c.h*3 {
+				t.Errorf("raw size %d", len(raw))
+			}
+			return z, nil
+		})
+		n := i + 1 + len(z)/3*4
+		if err != nil || len(str) < n || str[:n] != c.str[:n] {
+			t.Errorf("ToString got %q %v, want %q", str, err, c.str[:n])
+		}
+
+		// compress/zlib
+		str, err = ToString(bit.ToRGBA())
+		if err != nil || !strings.HasPrefix(str, c.str[:i+1]) {
+			t.Fatalf("ToString got %q %v", str, err)
+		}
+		back, err := FromString(str)
+		if err != nil || !samePixels(back, bit) {
+			t.Errorf("round trip %dx%d: %v", c.w, c.h, err)
+		}
+	}
+
+	if _, err := ToString(New(100000, 1)); err != ErrStringEncode {
+		t.Errorf("long dimension got %v", err)
+	}
+	if _, err := ToString(New(0, 0)); err != ErrStringEncode {
+		t.Errorf("empty bitmap got %v", err)
+	}
+}
+
+func TestFromStringError(t *testing.T) {
+	const data = "eNpjMM0CAADXAKA="
+
+	for _, c := range []struct {
+		str string
+		err error
+	}{
+		{"", ErrStringHeader},
+		{"a1,1," + data, ErrStringHeader},
+		{"b0,1," + data, ErrStringHeader},
+		{"b1,," + data, ErrStringHeader},
+		{"b1x,1," + data, ErrStringHeader},
+		{"b-1,1," + data, ErrStringHeader},
+		{"b1234567,1," + data, ErrStringHeader},
+		{"b1", ErrStringHeader},
+		{"b1,1", ErrStringDecompress},
+		{"b1,1,", ErrStringDecompress},
+		{"b1,1,AAAA" + data, ErrStringDecompress},
+		{"b1,2," + data, ErrStringSize},
+		{"b2,1," + data, ErrStringSize},
+		// the header accepts 6 digits and the leading zeros
+		{"b000001,01," + data, nil},
+		// the padding is skipped, the trailing data is ignored
+		{"b1,1,eNpjMM0CAADXAKA", nil},
+		{"b1,1," + data + "AAAA", nil},
+	} {
+		_, err := FromString(c.str)
+		if err != c.err {
+			t.Errorf("%q: got %v, want %v", c.str, err, c.err)
+		}
+	}
+}
@@ -0,0 +1,324 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// tilesPerWorker split the haystack to more tiles than workers,
+// so the fast workers take more tiles
+const tilesPerWorker = 4
+
+// scanMode is how the matches of a tile are collected
+type scanMode int
+
+const (
+	// scanFirst the first match in rows order
+	scanFirst scanMode = iota
+	// scanBest the best match
+	scanBest
+	// scanAll every match in rows order
+	scanAll
+)
+
+// tile is a band of the candidate rows of one searcher, the haystack
+// rows it reads overlap the next tile by the needle height
+type tile struct {
+	s      *searcher
+	y0, y1 int
+}
+
+// makeTiles split the searchers to tiles, in the sequential search order
+func makeTiles(list []*searcher, workers int) []tile {
+	var tiles []tile
+
+	for _, s := range list {
+		rows := s.rect.Dy()
+		n := 1
+		if workers > 1 {
+			n = workers * tilesPerWorker
+		}
+		if n > rows {
+			n = rows
+		}
+
+		step := (rows + n - 1) / n
+		for y := s.rect.Min.Y; y < s.rect.Max.Y; y += step {
+			y1 := y + step
+			if y1 > s.rect.Max.Y {
+				y1 = s.rect.Max.Y
+			}
+			tiles = append(tiles, tile{s: s, y0: y, y1: y1})
+		}
+	}
+
+	return tiles
+}
+
+// each calls fn for the matches of the tile in rows order, stop if fn
+// returns false or cancel returns true; return false if stopped
+func (t tile) each(cancel func() bool, fn func(r Result) bool) bool {
+	s := t.s
+	for y := t.y0; y < t.y1; y++ {
+		if cancel != nil && cancel() {
+			return false
+		}
+
+		for x := s.rect.Min.X; x < s.rect.Max.X; x++ {
+			score, ok := s.match(x, y)
+			if ok && !fn(s.result(x, y, score)) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// scan the tile in rows order, stop if cancel returns true
+func (t tile) scan(mode scanMode, cancel func() bool) []Result {
+	var res []Result
+
+	t.each(cancel, func(r Result) bool {
+		switch mode {
+		case scanFirst:
+			res = []Result{r}
+			return false
+		case scanBest:
+			if len(res) == 0 || better(r, res[0]) {
+				res = []Result{r}
+			}
+		default:
+			res = append(res, r)
+		}
+		return true
+	})
+
+	return res
+}
+
+// scanKeep scan all searchers on workers goroutines and call keep for
+// every match in the sequential order, until keep returns false;
+// the tiles after the stop are cancelled
+func scanKeep(list []*searcher, workers int, keep func(r Result) bool) {
+	tiles := makeTiles(list, workers)
+
+	if workers <= 1 {
+		for _, t := range tiles {
+			if !t.each(nil, keep) {
+				return
+			}
+		}
+
+		return
+	}
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		next  int64 = -1
+		stop        = int64(len(tiles)) // the tiles >= stop are not needed
+		front int64                     // the tiles before front are kept
+		out   = make([][]Result, len(tiles))
+		done  = make([]bool, len(tiles))
+	)
+
+	// flush keep the matches of the front tile i, false if stopped
+	flush := func(i int64, res []Result) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		for _, r := range res {
+			if !keep(r) {
+				atomic.StoreInt64(&stop, i+1)
+				return false
+			}
+		}
+
+		return true
+	}
+
+	worker := func() {
+		defer wg.Done()
+
+		for {
+			i := atomic.AddInt64(&next, 1)
+			if i >= atomic.LoadInt64(&stop) {
+				return
+			}
+
+			// buffer the matches until the tiles before are kept,
+			// then keep them as they are found
+			var buf []Result
+			isFront := false
+			tiles[i].each(func() bool {
+				return atomic.LoadInt64(&stop) <= i
+			}, func(r Result) bool {
+				buf = append(buf, r)
+				if !isFront {
+					isFront = atomic.LoadInt64(&front) == i
+				}
+				if !isFront {
+					return true
+				}
+
+				ok := flush(i, buf)
+				buf = buf[:0]
+				return ok
+			})
+
+			mu.Lock()
+			out[i], done[i] = buf, true
+			for f := atomic.LoadInt64(&front); f < atomic.LoadInt64(&stop) && done[f]; f++ {
+				for _, r := range out[f] {
+					if !keep(r) {
+						atomic.StoreInt64(&stop, f+1)
+						break
+					}
+				}
+				out[f] = nil
+				atomic.StoreInt64(&front, f+1)
+			}
+			mu.Unlock()
+		}
+	}
+
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go worker()
+	}
+	wg.Wait()
+}
+
+// runTiles call fn for the tiles 0 - n-1 on workers goroutines
+func runTiles(n, workers int, fn func(i int)) {
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+
+		return
+	}
+
+	var (
+		wg   sync.WaitGroup
+		next int64 = -1
+	)
+
+	if workers > n {
+		workers = n
+	}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+
+			for {
+				i := atomic.AddInt64(&next, 1)
+				if i >= int64(n) {
+					return
+				}
+				fn(int(i))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// scanTiles scan all searchers on workers goroutines, the results are
+// the same as the sequential search; in the scanFirst mode, the first
+// match cancels the tiles after it
+func scanTiles(list []*searcher, workers int, mode scanMode) []Result {
+	tiles := makeTiles(list, workers)
+	out := make([][]Result, len(tiles))
+
+	if workers <= 1 {
+		for i, t := range tiles {
+			out[i] = t.scan(mode, nil)
+			if mode == scanFirst && len(out[i]) > 0 {
+				break
+			}
+		}
+
+		return merge(out, mode)
+	}
+
+	var (
+		wg    sync.WaitGroup
+		next  int64 = -1
+		found       = int64(len(tiles))
+	)
+
+	worker := func() {
+		defer wg.Done()
+
+		for {
+			i := atomic.AddInt64(&next, 1)
+			if i >= int64(len(tiles)) {
+				return
+			}
+			if mode == scanFirst && atomic.LoadInt64(&found) < i {
+				return
+			}
+
+			var cancel func() bool
+			if mode == scanFirst {
+				cancel = func() bool { return atomic.LoadInt64(&found) < i }
+			}
+
+			out[i] = tiles[i].scan(mode, cancel)
+			if mode != scanFirst || len(out[i]) == 0 {
+				continue
+			}
+
+			for {
+				f := atomic.LoadInt64(&found)
+				if f <= i || atomic.CompareAndSwapInt64(&found, f, i) {
+					break
+				}
+			}
+		}
+	}
+
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go worker()
+	}
+	wg.Wait()
+
+	return merge(out, mode)
+}
+
+// merge the results of the tiles in the sequential order
+func merge(out [][]Result, mode scanMode) []Result {
+	var res []Result
+
+	for _, r := range out {
+		if len(r) == 0 {
+			continue
+		}
+
+		switch mode {
+		case scanFirst:
+			return r[:1]
+		case scanBest:
+			if len(res) == 0 || better(r[0], res[0]) {
+				res = r[:1]
+			}
+		default:
+			res = append(res, r...)
+		}
+	}
+
+	return res
+}
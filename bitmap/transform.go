@@ -0,0 +1,379 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+	"math"
+)
+
+// Interp is the resize interpolation
+type Interp int
+
+const (
+	// InterpNearest the nearest pixel, the colors are not mixed
+	InterpNearest Interp = iota
+	// InterpBilinear the bilinear filter, as the scaled search
+	InterpBilinear
+	// InterpArea the average of the covered pixels, the best
+	// to downscale without the aliasing
+	InterpArea
+)
+
+// remap make the w x h bitmap, the pixel (x, y) is the source
+// pixel fn(x, y)
+func remap(bit *Bitmap, w, h int, fn func(x, y int) (int, int)) *Bitmap {
+	dst := New(w, h)
+	bpp, dbpp := int(bit.BytesPerPixel), int(dst.BytesPerPixel)
+
+	for y := 0; y < h; y++ {
+		drow := dst.ImageBuffer[y*dst.Bytewidth:]
+		for x := 0; x < w; x++ {
+			sx, sy := fn(x, y)
+			copy(drow[x*dbpp:x*dbpp+3], bit.ImageBuffer[sy*bit.Bytewidth+sx*bpp:])
+		}
+	}
+
+	return dst
+}
+
+// Crop returns the rect of the image, in the image coordinates;
+// nil if the rect is out of the image
+func Crop(img image.Image, r image.Rectangle) *Bitmap {
+	bit := FromImage(img)
+	if bit == nil {
+		return nil
+	}
+
+	r = r.Sub(img.Bounds().Min).Intersect(bit.Bounds())
+	if r.Empty() {
+		return nil
+	}
+
+	return remap(bit, r.Dx(), r.Dy(), func(x, y int) (int, int) {
+		return r.Min.X + x, r.Min.Y + y
+	})
+}
+
+// Resize resize the image to w x h, the 0 width or height keeps
+// the aspect ratio; nil if both are 0
+func Resize(img image.Image, w, h int, interp Interp) *Bitmap {
+	bit := FromImage(img)
+	if bit == nil || bit.Width == 0 || bit.Height == 0 || w <= 0 && h <= 0 {
+		return nil
+	}
+
+	if w <= 0 {
+		w = int(float64(bit.Width)*float64(h)/float64(bit.Height) + 0.5)
+	}
+	if h <= 0 {
+		h = int(float64(bit.Height)*float64(w)/float64(bit.Width) + 0.5)
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+
+	switch interp {
+	case InterpBilinear:
+		return resample(bit, w, h)
+	case InterpArea:
+		return convolve(bit, areaTaps(bit.Width, w), areaTaps(bit.Height, h))
+	}
+
+	return remap(bit, w, h, func(x, y int) (int, int) {
+		return (2*x + 1) * bit.Width / (2 * w), (2*y + 1) * bit.Height / (2 * h)
+	})
+}
+
+// Rotate90 rotate the image n quarter turns clockwise,
+// the negative n is counterclockwise
+func Rotate90(img image.Image, n int) *Bitmap {
+	bit := FromImage(img)
+	if bit == nil {
+		return nil
+	}
+
+	w, h := bit.Width, bit.Height
+	switch (n%4 + 4) % 4 {
+	case 1:
+		return remap(bit, h, w, func(x, y int) (int, int) { return y, h - 1 - x })
+	case 2:
+		return remap(bit, w, h, func(x, y int) (int, int) { return w - 1 - x, h - 1 - y })
+	case 3:
+		return remap(bit, h, w, func(x, y int) (int, int) { return w - 1 - y, x })
+	}
+
+	return bit.Copy()
+}
+
+// Rotate rotate the image by the degrees clockwise with the bilinear
+// filter, the bitmap is enlarged to hold the rotated image and the
+// corners are the bg 0xRRGGBB color; the multiples of 90 are exact
+func Rotate(img image.Image, degrees float64, bg uint32) *Bitmap {
+	if q := degrees / 90; q == math.Trunc(q) {
+		return Rotate90(img, int(math.Mod(q, 4)))
+	}
+
+	bit := FromImage(img)
+	if bit == nil {
+		return nil
+	}
+
+	sin, cos := math.Sincos(degrees * math.Pi / 180)
+	sw, sh := float64(bit.Width), float64(bit.Height)
+	w := int(math.Ceil(math.Abs(sw*cos) + math.Abs(sh*sin) - 1e-6))
+	h := int(math.Ceil(math.Abs(sw*sin) + math.Abs(sh*cos) - 1e-6))
+
+	dst := New(w, h)
+	br, bgr, bb := uint8(bg>>16), uint8(bg>>8), uint8(bg)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			// the inverse rotation around the centers
+			dx, dy := float64(x)+0.5-float64(w)/2, float64(y)+0.5-float64(h)/2
+			fx := dx*cos + dy*sin + sw/2
+			fy := -dx*sin + dy*cos + sh/2
+			if fx < 0 || fy < 0 || fx >= sw || fy >= sh {
+				dst.SetRGB(x, y, br, bgr, bb)
+				continue
+			}
+
+			x0, wx := split(fx-0.5, bit.Width)
+			y0, wy := split(fy-0.5, bit.Height)
+			x1, y1 := clamp(x0+1, bit.Width), clamp(y0+1, bit.Height)
+
+			r00, g00, b00 := bit.RGBAt(x0, y0)
+			r10, g10, b10 := bit.RGBAt(x1, y0)
+			r01, g01, b01 := bit.RGBAt(x0, y1)
+			r11, g11, b11 := bit.RGBAt(x1, y1)
+			dst.SetRGB(x, y,
+				lerp2(r00, r10, r01, r11, wx, wy),
+				lerp2(g00, g10, g01, g11, wx, wy),
+				lerp2(b00, b10, b01, b11, wx, wy))
+		}
+	}
+
+	return dst
+}
+
+// FlipH flip the image horizontally, the left is the right
+func FlipH(img image.Image) *Bitmap {
+	bit := FromImage(img)
+	if bit == nil {
+		return nil
+	}
+
+	return remap(bit, bit.Width, bit.Height, func(x, y int) (int, int) {
+		return bit.Width - 1 - x, y
+	})
+}
+
+// FlipV flip the image vertically, the top is the bottom
+func FlipV(img image.Image) *Bitmap {
+	bit := FromImage(img)
+	if bit == nil {
+		return nil
+	}
+
+	return remap(bit, bit.Width, bit.Height, func(x, y int) (int, int) {
+		return x, bit.Height - 1 - y
+	})
+}
+
+// Gray returns the grayscale image, the luminance as color.GrayModel
+func Gray(img image.Image) *Bitmap {
+	bit := FromImage(img)
+	if bit == nil {
+		return nil
+	}
+
+	return gray(bit)
+}
+
+// Threshold returns the binary image, the pixels with the
+// luminance >= level are white, the others black
+func Threshold(img image.Image, level uint8) *Bitmap {
+	bit := FromImage(img)
+	if bit == nil {
+		return nil
+	}
+
+	return threshold(bit, level)
+}
+
+// Invert returns the negative image
+func Invert(img image.Image) *Bitmap {
+	bit := FromImage(img)
+	if bit == nil {
+		return nil
+	}
+
+	dst := bit.Copy()
+	bpp := int(dst.BytesPerPixel)
+	for y := 0; y < dst.Height; y++ {
+		row := dst.ImageBuffer[y*dst.Bytewidth:]
+		for x := 0; x < dst.Width*bpp; x += bpp {
+			row[x], row[x+1], row[x+2] = 0xff-row[x], 0xff-row[x+1], 0xff-row[x+2]
+		}
+	}
+
+	return dst
+}
+
+// Blur returns the Gaussian blur of the image, sigma is the standard
+// deviation in pixels; the border pixels are clamped
+func Blur(img image.Image, sigma float64) *Bitmap {
+	bit := FromImage(img)
+	if bit == nil {
+		return nil
+	}
+	if sigma <= 0 {
+		return bit.Copy()
+	}
+
+	return convolve(bit, gaussTaps(bit.Width, sigma), gaussTaps(bit.Height, sigma))
+}
+
+// Sharpen returns the unsharp mask of the image, the image plus
+// amount times the difference of the image and its Blur(sigma)
+func Sharpen(img image.Image, sigma, amount float64) *Bitmap {
+	bit := FromImage(img)
+	if bit == nil {
+		return nil
+	}
+
+	blur := Blur(bit, sigma)
+	dst := New(bit.Width, bit.Height)
+	bpp, bbpp, dbpp := int(bit.BytesPerPixel), int(blur.BytesPerPixel),
+		int(dst.BytesPerPixel)
+	for y := 0; y < bit.Height; y++ {
+		row := bit.ImageBuffer[y*bit.Bytewidth:]
+		brow := blur.ImageBuffer[y*blur.Bytewidth:]
+		drow := dst.ImageBuffer[y*dst.Bytewidth:]
+
+		for x := 0; x < bit.Width; x++ {
+			for c := 0; c < 3; c++ {
+				v := float64(row[x*bpp+c])
+				drow[x*dbpp+c] = clampByte(v + amount*(v-float64(brow[x*bbpp+c])))
+			}
+		}
+	}
+
+	return dst
+}
+
+func clampByte(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+
+	return uint8(v + 0.5)
+}
+
+// tap is the weight of a source pixel
+type tap struct {
+	i int
+	w float64
+}
+
+// convolve make the len(xt) x len(yt) bitmap, the pixel (x, y) is the
+// sum of the xt[x] taps of the rows and then the yt[y] taps
+func convolve(bit *Bitmap, xt, yt [][]tap) *Bitmap {
+	var (
+		w, h = len(xt), len(yt)
+		bpp  = int(bit.BytesPerPixel)
+		tmp  = make([]float64, bit.Height*w*3)
+	)
+
+	for y := 0; y < bit.Height; y++ {
+		row := bit.ImageBuffer[y*bit.Bytewidth:]
+		for x, taps := range xt {
+			var b, g, r float64
+			for _, t := range taps {
+				p := row[t.i*bpp:]
+				b += float64(p[0]) * t.w
+				g += float64(p[1]) * t.w
+				r += float64(p[2]) * t.w
+			}
+
+			o := (y*w + x) * 3
+			tmp[o], tmp[o+1], tmp[o+2] = b, g, r
+		}
+	}
+
+	dst := New(w, h)
+	dbpp := int(dst.BytesPerPixel)
+	for y, taps := range yt {
+		drow := dst.ImageBuffer[y*dst.Bytewidth:]
+		for x := 0; x < w; x++ {
+			var b, g, r float64
+			for _, t := range taps {
+				o := (t.i*w + x) * 3
+				b += tmp[o] * t.w
+				g += tmp[o+1] * t.w
+				r += tmp[o+2] * t.w
+			}
+
+			drow[x*dbpp], drow[x*dbpp+1], drow[x*dbpp+2] =
+				clampByte(b), clampByte(g), clampByte(r)
+		}
+	}
+
+	return dst
+}
+
+// areaTaps returns the taps of the n pixels resized to dn,
+// the weights are the covered parts of the source pixels
+func areaTaps(n, dn int) [][]tap {
+	taps := make([][]tap, dn)
+	s := float64(n) / float64(dn)
+
+	for i := range taps {
+		a, b := float64(i)*s, float64(i+1)*s
+		for j := int(a); j < n && float64(j) < b; j++ {
+			w := math.Min(b, float64(j+1)) - math.Max(a, float64(j))
+			if w > 1e-9 {
+				taps[i] = append(taps[i], tap{j, w / s})
+			}
+		}
+	}
+
+	return taps
+}
+
+// gaussTaps returns the Gaussian taps of the n pixels,
+// the radius is 3 sigma, the indexes are clamped
+func gaussTaps(n int, sigma float64) [][]tap {
+	r := int(math.Ceil(3 * sigma))
+	kernel := make([]float64, 2*r+1)
+
+	var sum float64
+	for i := range kernel {
+		d := float64(i - r)
+		kernel[i] = math.Exp(-d * d / (2 * sigma * sigma))
+		sum += kernel[i]
+	}
+
+	taps := make([][]tap, n)
+	for i := range taps {
+		taps[i] = make([]tap, len(kernel))
+		for k, w := range kernel {
+			taps[i][k] = tap{clamp(i+k-r, n), w / sum}
+		}
+	}
+
+	return taps
+}
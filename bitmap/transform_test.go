@@ -0,0 +1,142 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"image"
+	"testing"
+)
+
+func TestCrop(t *testing.T) {
+	bit := pattern(20, 10, 24, 3)
+
+	c := Crop(bit, image.Rect(5, 2, 9, 12))
+	if c.Width != 4 || c.Height != 8 || c.HexAt(0, 0) != bit.HexAt(5, 2) ||
+		c.HexAt(3, 7) != bit.HexAt(8, 9) {
+		t.Errorf("Crop got %dx%d", c.Width, c.Height)
+	}
+
+	// the rect is in the image coordinates
+	sub := bit.ToRGBA().SubImage(image.Rect(10, 5, 20, 10))
+	if c := Crop(sub, image.Rect(12, 6, 14, 7)); c == nil || c.Width != 2 ||
+		c.HexAt(1, 0) != bit.HexAt(13, 6) {
+		t.Errorf("Crop of sub image got %+v", c)
+	}
+	if Crop(bit, image.Rect(30, 0, 40, 5)) != nil {
+		t.Error("Crop out of the image should be nil")
+	}
+}
+
+func TestResize(t *testing.T) {
+	bit := New(4, 2)
+	fill(bit, image.Rect(0, 0, 2, 2), 0xff0000)
+	fill(bit, image.Rect(2, 0, 4, 2), 0x0000ff)
+	bit.SetRGB(3, 1, 0, 0xff, 0xff)
+
+	n := Resize(bit, 8, 0, InterpNearest)
+	if n.Width != 8 || n.Height != 4 || n.HexAt(3, 3) != 0xff0000 ||
+		n.HexAt(4, 0) != 0x0000ff || n.HexAt(7, 3) != 0x00ffff {
+		t.Errorf("nearest got %dx%d", n.Width, n.Height)
+	}
+
+	// the 2x2 blocks are averaged
+	a := Resize(bit, 2, 1, InterpArea)
+	if a.Width != 2 || a.Height != 1 || a.HexAt(0, 0) != 0xff0000 ||
+		a.HexAt(1, 0) != 0x0040ff {
+		t.Errorf("area got %dx%d %06x", a.Width, a.Height, a.HexAt(1, 0))
+	}
+	if u := Resize(bit, 12, 6, InterpArea); u.HexAt(0, 0) != 0xff0000 ||
+		u.HexAt(11, 5) != 0x00ffff {
+		t.Error("area upscale")
+	}
+
+	if b := Resize(bit, 0, 4, InterpBilinear); b.Width != 8 || b.Height != 4 {
+		t.Errorf("bilinear got %dx%d", b.Width, b.Height)
+	}
+	if Resize(bit, 0, 0, InterpNearest) != nil {
+		t.Error("the 0 size should be nil")
+	}
+}
+
+func TestRotate(t *testing.T) {
+	bit := pattern(3, 2, 32, 1)
+	w, h := bit.Width, bit.Height
+
+	r := Rotate90(bit, 1)
+	if r.Width != h || r.Height != w || r.HexAt(h-1, 0) != bit.HexAt(0, 0) ||
+		r.HexAt(0, 0) != bit.HexAt(0, h-1) {
+		t.Error("Rotate90 1")
+	}
+	if !samePixels(Rotate90(bit, -1), Rotate90(bit, 3)) ||
+		!samePixels(Rotate90(Rotate90(bit, 2), 2), bit) ||
+		!samePixels(Rotate(bit, 90, 0), r) || !samePixels(Rotate(bit, -360, 0), bit) {
+		t.Error("Rotate90 turns")
+	}
+	if r := Rotate90(bit, 2); r.HexAt(0, 0) != bit.HexAt(w-1, h-1) {
+		t.Error("Rotate90 2")
+	}
+
+	sq := New(10, 10)
+	fill(sq, sq.Bounds(), 0x00ff00)
+	d := Rotate(sq, 45, 0xffffff)
+	if d.Width != 15 || d.Height != 15 || d.HexAt(7, 7) != 0x00ff00 ||
+		d.HexAt(0, 0) != 0xffffff || d.HexAt(14, 14) != 0xffffff {
+		t.Errorf("Rotate 45 got %dx%d", d.Width, d.Height)
+	}
+}
+
+func TestFlipInvert(t *testing.T) {
+	bit := pattern(5, 3, 24, 2)
+
+	h, v := FlipH(bit), FlipV(bit)
+	if h.HexAt(0, 1) != bit.HexAt(4, 1) || v.HexAt(1, 0) != bit.HexAt(1, 2) ||
+		!samePixels(FlipH(h), bit) {
+		t.Error("Flip")
+	}
+
+	inv := Invert(bit)
+	if inv.HexAt(2, 2) != 0xffffff^bit.HexAt(2, 2) || !samePixels(Invert(inv), bit) {
+		t.Error("Invert")
+	}
+
+	if g := Gray(bit); g.HexAt(1, 1)>>16 != g.HexAt(1, 1)&0xff {
+		t.Error("Gray")
+	}
+	if th := Threshold(bit, 128); th.HexAt(0, 0) != 0 && th.HexAt(0, 0) != 0xffffff {
+		t.Error("Threshold")
+	}
+}
+
+func TestBlurSharpen(t *testing.T) {
+	flat := New(9, 9)
+	fill(flat, flat.Bounds(), 0x808080)
+	if !samePixels(Blur(flat, 1.5), flat) || !samePixels(Sharpen(flat, 1, 2), flat) {
+		t.Error("the flat image is changed")
+	}
+
+	dot := New(9, 9)
+	dot.SetRGB(4, 4, 0xff, 0xff, 0xff)
+	b := Blur(dot, 1)
+	c, n, e := b.HexAt(4, 4)&0xff, b.HexAt(4, 3)&0xff, b.HexAt(5, 4)&0xff
+	if c == 0xff || c <= n || n != e || n == 0 || b.HexAt(4, 5) != b.HexAt(3, 4) {
+		t.Errorf("Blur got %d %d %d", c, n, e)
+	}
+
+	// the step edge is steeper
+	step := New(10, 1)
+	fill(step, image.Rect(0, 0, 5, 1), 0x606060)
+	fill(step, image.Rect(5, 0, 10, 1), 0xa0a0a0)
+	s := Sharpen(step, 1, 1)
+	if s.HexAt(4, 0)&0xff >= 0x60 || s.HexAt(5, 0)&0xff <= 0xa0 ||
+		s.HexAt(0, 0) != 0x606060 {
+		t.Errorf("Sharpen got %06x %06x", s.HexAt(4, 0), s.HexAt(5, 0))
+	}
+}
@@ -0,0 +1,286 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// VideoType is the animation or video container type
+type VideoType int
+
+const (
+	// InvalidVideoType unsupported video type
+	InvalidVideoType VideoType = iota
+	// GIFVideoType animated gif
+	GIFVideoType
+	// APNGVideoType animated png
+	APNGVideoType
+	// AVIVideoType mjpeg avi
+	AVIVideoType
+)
+
+// defaultFPS is the default AVI frame rate
+const defaultFPS = 10
+
+// The video writer errors
+var (
+	ErrVideoClosed = errors.New("Video writer is closed")
+	ErrVideoSize   = errors.New("Video frame or file is too large")
+)
+
+// VideoOptions is the video writer options
+type VideoOptions struct {
+	// Type the video type, InvalidVideoType selects
+	// the type by the file extension
+	Type VideoType
+	// FPS the AVI frame rate, default 10; the frame delays are
+	// rounded to the frames
+	FPS float64
+	// Quality the AVI JPEG quality 1 - 100, 0 is DefaultQuality
+	Quality int
+	// Dedup the unchanged frames are merged to the previous frame,
+	// as a longer delay
+	Dedup bool
+}
+
+// VideoWriter streams the frames to a video; the frames are the
+// same size, the writer is not safe for the concurrent use
+type VideoWriter interface {
+	// WriteFrame write the frame shown for the delay; the
+	// frame must not be modified after the call
+	WriteFrame(img image.Image, delay time.Duration) error
+	// Close finish the video
+	Close() error
+}
+
+// VideoTypeFromExtension returns the video type of the file extension
+// or path: gif, png (or apng) and avi
+func VideoTypeFromExtension(path string) VideoType {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if ext == "" {
+		ext = strings.ToLower(path)
+	}
+
+	switch ext {
+	case "gif":
+		return GIFVideoType
+	case "png", "apng":
+		return APNGVideoType
+	case "avi":
+		return AVIVideoType
+	default:
+		return InvalidVideoType
+	}
+}
+
+// NewVideoWriter returns the video writer of opt.Type to w,
+// the headers are updated by seeking on Close
+func NewVideoWriter(w io.WriteSeeker, opt *VideoOptions) (VideoWriter, error) {
+	o := VideoOptions{}
+	if opt != nil {
+		o = *opt
+	}
+	if o.FPS <= 0 {
+		o.FPS = defaultFPS
+	}
+	if o.Quality <= 0 {
+		o.Quality = DefaultQuality
+	}
+
+	var fw frameWriter
+	switch o.Type {
+	case GIFVideoType:
+		fw = &gifWriter{w: w}
+	case APNGVideoType:
+		fw = &apngWriter{w: w}
+	case AVIVideoType:
+		fw = &aviWriter{w: w, fps: o.FPS, quality: o.Quality}
+	default:
+		return nil, ErrUnsupportedType
+	}
+
+	return &videoWriter{fw: fw, dedup: o.Dedup}, nil
+}
+
+// CreateVideo create the video file, the type is selected by the
+// file extension if opt.Type is InvalidVideoType; Close closes the file
+//
+//	w, err := bitmap.CreateVideo("run.gif", &bitmap.VideoOptions{Dedup: true})
+func CreateVideo(path string, opt *VideoOptions) (VideoWriter, error) {
+	o := VideoOptions{}
+	if opt != nil {
+		o = *opt
+	}
+	if o.Type == InvalidVideoType {
+		o.Type = VideoTypeFromExtension(path)
+	}
+	if o.Type == InvalidVideoType || o.Type > AVIVideoType {
+		return nil, ErrUnsupportedType
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err := NewVideoWriter(f, &o)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w.(*videoWriter).file = f
+
+	return w, nil
+}
+
+// frameWriter writes the frames of one container, prev is
+// the previous frame, nil for the first frame
+type frameWriter interface {
+	frame(bit, prev *Bitmap, delay time.Duration) error
+	close() error
+}
+
+// videoWriter holds the pending frame to merge the unchanged
+// frames, its delay is known on the next changed frame
+type videoWriter struct {
+	fw    frameWriter
+	file  *os.File
+	dedup bool
+
+	prev    *Bitmap
+	pending *Bitmap
+	delay   time.Duration
+	closed  bool
+	err     error
+}
+
+func (v *videoWriter) WriteFrame(img image.Image, delay time.Duration) error {
+	if v.closed {
+		return ErrVideoClosed
+	}
+	if v.err != nil {
+		return v.err
+	}
+
+	bit := FromImage(img)
+	if bit == nil || bit.Width <= 0 || bit.Height <= 0 {
+		return ErrSizeMismatch
+	}
+	if v.pending != nil && (bit.Width != v.pending.Width ||
+		bit.Height != v.pending.Height) {
+		return ErrSizeMismatch
+	}
+
+	if v.dedup && v.pending != nil && Equal(bit, v.pending) {
+		v.delay += delay
+		return nil
+	}
+
+	if err := v.flush(); err != nil {
+		return err
+	}
+	v.pending, v.delay = bit, delay
+
+	return nil
+}
+
+// flush write the pending frame
+func (v *videoWriter) flush() error {
+	if v.pending == nil {
+		return nil
+	}
+
+	v.err = v.fw.frame(v.pending, v.prev, v.delay)
+	v.prev, v.pending = v.pending, nil
+
+	return v.err
+}
+
+func (v *videoWriter) Close() error {
+	if v.closed {
+		return ErrVideoClosed
+	}
+	v.closed = true
+
+	err := v.err
+	if err == nil {
+		err = v.flush()
+	}
+	if err == nil {
+		err = v.fw.close()
+	}
+
+	if v.file != nil {
+		if cerr := v.file.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	return err
+}
+
+// diffBounds returns the bounds of the changed pixels
+// of the bitmaps of the same size
+func diffBounds(a, b *Bitmap) image.Rectangle {
+	var (
+		r    image.Rectangle
+		n    = a.Width * int(a.BytesPerPixel)
+		same = a.BytesPerPixel == b.BytesPerPixel
+	)
+	for y := 0; y < a.Height; y++ {
+		if same && bytes.Equal(a.ImageBuffer[y*a.Bytewidth:y*a.Bytewidth+n],
+			b.ImageBuffer[y*b.Bytewidth:y*b.Bytewidth+n]) {
+			continue
+		}
+
+		x0 := -1
+		for x := 0; x < a.Width; x++ {
+			if a.HexAt(x, y) != b.HexAt(x, y) {
+				x0 = x
+				break
+			}
+		}
+		if x0 < 0 {
+			continue
+		}
+
+		x1 := a.Width - 1
+		for x1 > x0 && a.HexAt(x1, y) == b.HexAt(x1, y) {
+			x1--
+		}
+		r = r.Union(image.Rect(x0, y, x1+1, y+1))
+	}
+
+	return r
+}
+
+// changedRect returns the rect of the frame to write, the whole
+// frame for the first frame; one pixel for the unchanged frame
+func changedRect(bit, prev *Bitmap) image.Rectangle {
+	if prev == nil {
+		return bit.Bounds()
+	}
+
+	r := diffBounds(bit, prev)
+	if r.Empty() {
+		r = image.Rect(0, 0, 1, 1)
+	}
+
+	return r
+}
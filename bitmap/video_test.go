@@ -0,0 +1,241 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package bitmap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+	"time"
+)
+
+// seekBuf is an in-memory io.WriteSeeker
+type seekBuf struct {
+	data []byte
+	pos  int
+}
+
+func (s *seekBuf) Write(p []byte) (int, error) {
+	if end := s.pos + len(p); end > len(s.data) {
+		s.data = append(s.data, make([]byte, end-len(s.data))...)
+	}
+	copy(s.data[s.pos:], p)
+	s.pos += len(p)
+
+	return len(p), nil
+}
+
+func (s *seekBuf) Seek(off int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+		s.pos = int(off)
+	case io.SeekCurrent:
+		s.pos += int(off)
+	case io.SeekEnd:
+		s.pos = len(s.data) + int(off)
+	}
+
+	return int64(s.pos), nil
+}
+
+// frames returns the recorded frames: a, the same a and b,
+// b is changed in the rect (10, 5)-(14, 8)
+func frames() (a, b *Bitmap) {
+	a = New(40, 20)
+	fill(a, a.Bounds(), 0xf0f0f0)
+	fill(a, image.Rect(2, 2, 8, 6), 0x3366cc)
+
+	b = a.Copy()
+	fill(b, image.Rect(10, 5, 14, 8), 0xcc0000)
+
+	return a, b
+}
+
+func writeVideo(t *testing.T, opt *VideoOptions, imgs ...image.Image) []byte {
+	var buf seekBuf
+	w, err := NewVideoWriter(&buf, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, img := range imgs {
+		if err := w.WriteFrame(img, 100*time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.data
+}
+
+func TestVideoGIF(t *testing.T) {
+	a, b := frames()
+
+	data := writeVideo(t, &VideoOptions{Type: GIFVideoType, Dedup: true}, a, a.Copy(), b)
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Image) != 2 || g.Delay[0] != 20 || g.Delay[1] != 10 || g.LoopCount != 0 {
+		t.Fatalf("gif got %d frames, delays %v", len(g.Image), g.Delay)
+	}
+	if r := g.Image[1].Bounds(); r != image.Rect(10, 5, 14, 8) {
+		t.Errorf("the changed frame got %v", r)
+	}
+
+	// the frames are drawn over the previous frames
+	canvas := image.NewRGBA(a.Bounds())
+	for _, p := range g.Image {
+		draw.Draw(canvas, p.Bounds(), p, p.Bounds().Min, draw.Src)
+	}
+	if !samePixels(FromImage(canvas), b) {
+		t.Error("the composed gif is not the last frame")
+	}
+
+	data = writeVideo(t, &VideoOptions{Type: GIFVideoType}, a, a.Copy(), b)
+	if g, err = gif.DecodeAll(bytes.NewReader(data)); err != nil || len(g.Image) != 3 ||
+		g.Image[1].Bounds().Dx() != 1 {
+		t.Errorf("gif without dedup got %v", err)
+	}
+
+	// more than 256 colors
+	n := noise(30, 20, 1)
+	data = writeVideo(t, &VideoOptions{Type: GIFVideoType}, n)
+	if g, err = gif.DecodeAll(bytes.NewReader(data)); err != nil || len(g.Image) != 1 {
+		t.Errorf("gif of noise got %v", err)
+	}
+}
+
+func TestVideoAPNG(t *testing.T) {
+	a, b := frames()
+
+	data := writeVideo(t, &VideoOptions{Type: APNGVideoType, Dedup: true}, a, a, b)
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !samePixels(FromImage(img), a) {
+		t.Error("the default image is not the first frame")
+	}
+
+	var chunks []string
+	for p := 8; p < len(data); {
+		n := int(binary.BigEndian.Uint32(data[p:]))
+		typ := string(data[p+4 : p+8])
+		chunks = append(chunks, typ)
+
+		body := data[p+8 : p+8+n]
+		switch typ {
+		case "acTL":
+			if binary.BigEndian.Uint32(body) != 2 {
+				t.Errorf("acTL frames got %d", binary.BigEndian.Uint32(body))
+			}
+		case "fcTL":
+			seq := binary.BigEndian.Uint32(body)
+			delay := [2]uint16{binary.BigEndian.Uint16(body[20:]),
+				binary.BigEndian.Uint16(body[22:])}
+			if seq == 0 && delay != [2]uint16{200, 1000} ||
+				seq == 1 && (delay != [2]uint16{100, 1000} ||
+					binary.BigEndian.Uint32(body[12:]) != 10 ||
+					binary.BigEndian.Uint32(body[4:]) != 4) {
+				t.Errorf("fcTL %d got %v % x", seq, delay, body)
+			}
+		case "fdAT":
+			if seq := binary.BigEndian.Uint32(body); seq != 2 {
+				t.Errorf("fdAT seq got %d", seq)
+			}
+		}
+		p += 12 + n
+	}
+
+	want := "[IHDR acTL fcTL IDAT fcTL fdAT IEND]"
+	if got := fmt.Sprint(chunks); got != want {
+		t.Errorf("chunks got %s, want %s", got, want)
+	}
+}
+
+func TestVideoAVI(t *testing.T) {
+	a, b := frames()
+
+	// the 200ms delay is 2 frames at 10 fps
+	data := writeVideo(t, &VideoOptions{Type: AVIVideoType, Dedup: true}, a, a, b)
+	le := binary.LittleEndian
+	if string(data[:4]) != "RIFF" || int(le.Uint32(data[4:])) != len(data)-8 ||
+		string(data[8:12]) != "AVI " {
+		t.Fatalf("bad RIFF header % x", data[:12])
+	}
+	// avih dwTotalFrames, strh dwLength
+	if le.Uint32(data[48:]) != 3 || le.Uint32(data[140:]) != 3 {
+		t.Errorf("frames got %d %d", le.Uint32(data[48:]), le.Uint32(data[140:]))
+	}
+	if string(data[aviMoviOffset:aviMoviOffset+4]) != "movi" ||
+		string(data[aviHeaderSize:aviHeaderSize+4]) != "00dc" {
+		t.Fatal("bad movi list")
+	}
+
+	size := int(le.Uint32(data[aviHeaderSize+4:]))
+	img, err := jpeg.Decode(bytes.NewReader(data[aviHeaderSize+8 : aviHeaderSize+8+size]))
+	if err != nil || img.Bounds() != a.Bounds() {
+		t.Fatalf("the first frame got %v", err)
+	}
+
+	// the second chunk is the repeated frame
+	p := aviHeaderSize + 8 + size + size%2
+	if string(data[p:p+4]) != "00dc" || le.Uint32(data[p+4:]) != 0 {
+		t.Errorf("the repeated frame got % x", data[p:p+8])
+	}
+
+	movi := int(le.Uint32(data[aviMoviOffset-4:]))
+	idx := data[aviMoviOffset+movi:]
+	if string(idx[:4]) != "idx1" || le.Uint32(idx[4:]) != 3*16 ||
+		le.Uint32(idx[8+4:]) != 0x10 || le.Uint32(idx[8+16+4:]) != 0 ||
+		int(le.Uint32(idx[8+16+8:])) != p-aviMoviOffset {
+		t.Errorf("bad idx1 % x", idx)
+	}
+}
+
+func TestVideoWriterError(t *testing.T) {
+	var buf seekBuf
+	w, err := NewVideoWriter(&buf, &VideoOptions{Type: GIFVideoType})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.WriteFrame(New(4, 4), time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteFrame(New(5, 4), time.Second); err != ErrSizeMismatch {
+		t.Errorf("the other size got %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteFrame(New(4, 4), time.Second); err != ErrVideoClosed {
+		t.Errorf("write after close got %v", err)
+	}
+
+	if _, err := NewVideoWriter(&buf, nil); err != ErrUnsupportedType {
+		t.Errorf("no type got %v", err)
+	}
+	if VideoTypeFromExtension("run.AVI") != AVIVideoType ||
+		VideoTypeFromExtension("run.apng") != APNGVideoType {
+		t.Error("VideoTypeFromExtension")
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package robotgo
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"github.com/go-vgo/robotgo/bitmap"
+)
+
+func TestCaptureNoLeak(t *testing.T) {
+	if len(GetDisplays()) == 0 {
+		t.Skip("no display")
+	}
+
+	// the first capture creates the cached capture resources
+	needle := CaptureImage(0, 0, 8, 8)
+	if needle == nil {
+		t.Fatal("the capture failed")
+	}
+
+	n := CBitmapCount()
+	for i := 0; i < 3; i++ {
+		if CaptureImage(0, 0, 64, 64) == nil {
+			t.Fatal("CaptureImage failed")
+		}
+		GetPixelColor(1, 1)
+		FindImage(needle, &bitmap.Options{Rect: image.Rect(0, 0, 64, 64)})
+
+		c := Capture(0, 0, 16, 16)
+		if c == nil {
+			t.Fatal("Capture failed")
+		}
+		c.Free()
+	}
+
+	if got := CBitmapCount(); got != n {
+		t.Errorf("the live C bitmaps got %d, want %d", got, n)
+	}
+}
+
+// The capture benchmarks need a display of at least 3840 x 2160,
+// the smaller sizes are skipped, e.g.
+//
+//	Xvfb :99 -screen 0 3840x2160x24 &
+//	DISPLAY=:99 go test -run NONE -bench Capture
+//
+// The fps metric is the captures (frames) per second.
+
+func benchCapture(b *testing.B, w, h int, shm bool) {
+	if bounds := GetVirtualBounds(); bounds.Dx() < w || bounds.Dy() < h {
+		b.Skipf("the display %v is smaller than %d x %d", bounds, w, h)
+	}
+
+	SetXShm(shm)
+	defer SetXShm(true)
+	if shm && !HasXShm() {
+		b.Skip("the MIT-SHM is unavailable")
+	}
+
+	b.ResetTimer()
+	start := time.Now()
+	for i := 0; i < b.N; i++ {
+		bit := CaptureScreen(0, 0, w, h)
+		if bit == nil {
+			b.Fatal("the capture failed")
+		}
+		FreeBitmap(bit)
+	}
+
+	b.ReportMetric(float64(b.N)/time.Since(start).Seconds(), "fps")
+}
+
+func BenchmarkCapture1080pXGetImage(b *testing.B) {
+	benchCapture(b, 1920, 1080, false)
+}
+
+func BenchmarkCapture1080pXShm(b *testing.B) {
+	benchCapture(b, 1920, 1080, true)
+}
+
+func BenchmarkCapture4KXGetImage(b *testing.B) {
+	benchCapture(b, 3840, 2160, false)
+}
+
+func BenchmarkCapture4KXShm(b *testing.B) {
+	benchCapture(b, 3840, 2160, true)
+}
@@ -5,7 +5,7 @@
 // Package clipboard read/write on clipboard
 package clipboard
 
-// import ()
+import "image"
 
 // ReadAll read string from clipboard
 func ReadAll() (string, error) {
@@ -17,6 +17,16 @@ func WriteAll(text string) error {
 	return writeAll(text)
 }
 
+// ReadImage read the image/png or image/bmp image from clipboard
+func ReadImage() (image.Image, error) {
+	return readImage()
+}
+
+// WriteImage write the image to clipboard as image/png
+func WriteImage(img image.Image) error {
+	return writeImage(img)
+}
+
 // Unsupported might be set true during clipboard init, to help callers decide
 // whether or not to offer clipboard options.
 var Unsupported bool
@@ -0,0 +1,22 @@
+// Copyright 2013 @atotto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build darwin windows
+
+package clipboard
+
+import (
+	"errors"
+	"image"
+)
+
+var errImageUnsupported = errors.New("Clipboard image is not supported on this platform")
+
+func readImage() (image.Image, error) {
+	return nil, errImageUnsupported
+}
+
+func writeImage(img image.Image) error {
+	return errImageUnsupported
+}
@@ -7,8 +7,15 @@
 package clipboard
 
 import (
+	"bytes"
 	"errors"
+	"image"
+	"image/png"
 	"os/exec"
+	"strings"
+
+	// the image/bmp targets
+	_ "golang.org/x/image/bmp"
 )
 
 const (
@@ -30,6 +37,11 @@ var (
 	xclipCopyArgs  = []string{xclip, "-in", "-selection", "clipboard"}
 
 	errMissingCommands = errors.New("No clipboard utilities available. Please install xsel or xclip")
+	errMissingXclip    = errors.New("No clipboard image utility available. Please install xclip")
+	errNoImage         = errors.New("No image/png or image/bmp in clipboard")
+
+	// imageTargets are the readable image targets, by preference
+	imageTargets = []string{"image/png", "image/bmp", "image/x-bmp", "image/x-ms-bmp"}
 )
 
 func init() {
@@ -97,3 +109,77 @@ func writeAll(text string) error {
 	}
 	return copyCmd.Wait()
 }
+
+// xclipArgs returns the xclip args of the selection and the target,
+// the images need xclip, xsel does not support the targets
+func xclipArgs(dir, target string) ([]string, error) {
+	if _, err := exec.LookPath(xclip); err != nil {
+		return nil, errMissingXclip
+	}
+
+	selection := "clipboard"
+	if Primary {
+		selection = "primary"
+	}
+
+	return []string{dir, "-selection", selection, "-t", target}, nil
+}
+
+// imageTarget returns the preferred image target of the
+// TARGETS output, "" if there is no image
+func imageTarget(targets []byte) string {
+	has := make(map[string]bool)
+	for _, t := range strings.Fields(string(targets)) {
+		has[t] = true
+	}
+
+	for _, t := range imageTargets {
+		if has[t] {
+			return t
+		}
+	}
+
+	return ""
+}
+
+func readImage() (image.Image, error) {
+	args, err := xclipArgs("-out", "TARGETS")
+	if err != nil {
+		return nil, err
+	}
+	targets, err := exec.Command(xclip, args...).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	target := imageTarget(targets)
+	if target == "" {
+		return nil, errNoImage
+	}
+
+	args, _ = xclipArgs("-out", target)
+	out, err := exec.Command(xclip, args...).Output()
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(out))
+	return img, err
+}
+
+func writeImage(img image.Image) error {
+	args, err := xclipArgs("-in", "image/png")
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return err
+	}
+
+	// xclip serves the selection in the background
+	copyCmd := exec.Command(xclip, args...)
+	copyCmd.Stdin = &buf
+	return copyCmd.Run()
+}
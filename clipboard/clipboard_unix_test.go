@@ -0,0 +1,22 @@
+// Copyright 2013 @atotto. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// +build freebsd linux netbsd openbsd solaris dragonfly
+
+package clipboard
+
+import "testing"
+
+func TestImageTarget(t *testing.T) {
+	targets := "TIMESTAMP\nTARGETS\nimage/bmp\nimage/png\nSTRING\n"
+	if target := imageTarget([]byte(targets)); target != "image/png" {
+		t.Errorf("want image/png, got %q", target)
+	}
+	if target := imageTarget([]byte("TARGETS\nimage/x-ms-bmp\n")); target != "image/x-ms-bmp" {
+		t.Errorf("want image/x-ms-bmp, got %q", target)
+	}
+	if target := imageTarget([]byte("UTF8_STRING\nTEXT\n")); target != "" {
+		t.Errorf("want no target, got %q", target)
+	}
+}
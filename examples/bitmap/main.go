@@ -12,9 +12,11 @@ package main
 
 import (
 	"fmt"
+	"image"
 	"log"
 
 	"github.com/go-vgo/robotgo"
+	"github.com/go-vgo/robotgo/bitmap"
 	"github.com/vcaesar/imgo"
 	// "go-vgo/robotgo"
 )
@@ -29,57 +31,85 @@ func main() {
 	fmt.Println("abitMap...", abitMap)
 
 	// gets part of the screen
-	bitmap := robotgo.CaptureScreen(100, 200, 30, 40)
-	fmt.Println("CaptureScreen...", bitmap)
+	cbit := robotgo.CaptureScreen(100, 200, 30, 40)
+	fmt.Println("CaptureScreen...", cbit)
 
-	gbit := robotgo.ToBitmap(bitmap)
+	gbit := robotgo.ToBitmap(cbit)
 	fmt.Println("go bitmap", gbit, gbit.Width)
 
-	// searches for needle in bitmap
-	fx, fy := robotgo.FindBit(bitmap)
-	fmt.Println("FindBitmap------", fx, fy)
+	var fx, fy int
+
+	// gets part of the screen as a go bitmap
+	bit := robotgo.CaptureImage(1, 2, 40, 40)
+	fmt.Println("CaptureImage...", bit.Bounds())
+	screen := robotgo.CaptureImage()
 
-	color := robotgo.GetColor(bitmap, 1, 2)
+	color := bit.HexAt(1, 2)
 	fmt.Println("color...", color)
-	cx, cy := robotgo.FindColor(bitmap, robotgo.CHex(color), 1.0)
+	cx, cy := robotgo.FindColor(robotgo.CHex(color), bit, 1.0)
 	fmt.Println("pos...", cx, cy)
-	cx, cy = robotgo.FindColor(bitmap, 0xAADCDC)
+	cx, cy = robotgo.FindColor(0xAADCDC)
 	fmt.Println("pos...", cx, cy)
 	cx, cy = robotgo.FindColorCS(388, 179, 300, 300, 0xAADCDC)
 	fmt.Println("pos...", cx, cy)
 
-	cnt := robotgo.CountColor(bitmap, 0xAADCDC)
+	cnt := robotgo.CountColor(0xAADCDC, screen)
 	fmt.Println("count...", cnt)
 	cnt1 := robotgo.CountColorCS(10, 20, 30, 40, 0xAADCDC)
 	fmt.Println("count...", cnt1)
 
-	count := robotgo.CountBitmap(abitMap, bitmap)
+	// every pixel of the color, and the connected blobs of the color
+	points := robotgo.FindEveryColor(0xAADCDC, &bitmap.ColorOptions{
+		Rect: image.Rect(0, 0, 400, 300),
+	})
+	fmt.Println("FindEveryColor...", len(points))
+	blobs := robotgo.FindColorBlobs(0xAADCDC, &bitmap.ColorOptions{
+		Tolerance: 0.05,
+		MinCount:  20,
+	})
+	for _, b := range blobs {
+		fmt.Println("blob...", b.Rect, b.Count, b.Center())
+	}
+
+	count := robotgo.CountBitmap(bit, screen)
 	fmt.Println("count...", count)
 
-	bit := robotgo.CaptureScreen(1, 2, 40, 40)
-	fmt.Println("CaptureScreen...", bit)
+	// searches for needle in the screen or in a bitmap
 	fx, fy = robotgo.FindBitmap(bit)
 	fmt.Println("FindBitmap------", fx, fy)
-	fx, fy = robotgo.FindBitmap(bit, bitmap)
+	fx, fy = robotgo.FindBitmap(bit, screen, 0.1)
 	fmt.Println("FindBitmap------", fx, fy)
 
-	abool := robotgo.PointInBounds(bitmap, 1, 2)
+	// searches in a rect with a score
+	res, ok := bitmap.Find(screen, bit, &bitmap.Options{
+		Tolerance: 0.1,
+		Rect:      image.Rect(0, 0, 200, 200),
+	})
+	fmt.Println("bitmap.Find...", res.X, res.Y, res.Score, ok)
+
+	every := robotgo.FindEveryBitmap(bit, screen, 0.1, 10)
+	fmt.Println("FindEveryBitmap...", every)
+
+	abool := robotgo.PointInBounds(cbit, 1, 2)
 	fmt.Println("point in bounds...", abool)
 
 	// returns new bitmap object created from a portion of another
-	bitpos := robotgo.GetPortion(bitmap, 10, 10, 11, 10)
+	bitpos := robotgo.GetPortion(cbit, 10, 10, 11, 10)
 	fmt.Println(bitpos)
 
-	// creates bitmap from string by bitmap
-	bitstr := robotgo.TostringBitmap(bitmap)
+	// creates the "b<width>,<height>,<data>" string of the bitmap
+	bitstr := robotgo.TostringBitmap(bit)
 	fmt.Println("bitstr...", bitstr)
 
-	// sbitmap := robotgo.BitmapFromstring(bitstr, 2)
-	// fmt.Println("...", sbitmap)
+	sbitmap, err := robotgo.BitmapStr(bitstr)
+	if err != nil {
+		log.Println("bitmap from string ", err)
+	}
+	fmt.Println("BitmapStr...", sbitmap.Bounds())
 
 	// saves image to absolute filepath in the given format
-	robotgo.SaveBitmap(bitmap, "test.png")
-	robotgo.SaveBitmap(bitmap, "test31.tif", 1)
+	robotgo.SaveBitmap(bit, "test.png")
+	robotgo.SaveBitmap(bit, "test31.bmp", 2)
 
 	img, name, err := robotgo.DecodeImg("test.png")
 	if err != nil {
@@ -95,16 +125,16 @@ func main() {
 	w, h = imgo.GetSize("test.png")
 	fmt.Println("image width and hight ", w, h)
 
-	// convert image
-	robotgo.Convert("test.png", "test.tif")
-
 	// open image bitmap
-	openbit := robotgo.OpenBitmap("test.tif")
-	fmt.Println("openBitmap...", openbit)
+	openbit, err := robotgo.OpenBitmap("test31.bmp")
+	if err != nil {
+		log.Println("open bitmap ", err)
+	}
+	fmt.Println("openBitmap...", openbit.Bounds())
 
 	fx, fy = robotgo.FindBitmap(openbit)
 	fmt.Println("FindBitmap------", fx, fy)
 
-	fx, fy = robotgo.FindPic("test.tif")
+	fx, fy = robotgo.FindPic("test.png")
 	fmt.Println("FindPic------", fx, fy)
 }
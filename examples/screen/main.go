@@ -27,6 +27,9 @@ func main() {
 	gbit := robotgo.ToBitmap(abitMap)
 	fmt.Println("bitmap...", gbit.Width)
 
+	img := robotgo.CaptureImage(10, 20, 100, 100)
+	fmt.Println("CaptureImage...", img.Bounds(), img.ToRGBA().Stride)
+
 	gbitMap := robotgo.GoCaptureScreen()
 	fmt.Println("GoCaptureScreen...", gbitMap.Width)
 	// fmt.Println("...", gbitmap.Width, gbitmap.BytesPerPixel)
@@ -31,7 +31,7 @@ package robotgo
 //#elif defined(USE_X11)
 	// Drop -std=c11
 	#cgo linux CFLAGS: -I/usr/src
-	#cgo linux LDFLAGS: -L/usr/src -lpng -lz -lX11 -lXtst -lX11-xcb -lxcb
+	#cgo linux LDFLAGS: -L/usr/src -lpng -lz -lX11 -lXtst -lXext -lXrandr -lX11-xcb -lxcb
 	#cgo linux LDFLAGS: -lxcb-xkb -lxkbcommon -lxkbcommon-x11 -lm
 //#endif
 	// #cgo windows LDFLAGS: -lgdi32 -luser32 -lpng -lz
@@ -45,21 +45,26 @@ package robotgo
 // #include "bitmap/goBitmap.h"
 // #include "event/goEvent.h"
 #include "window/goWindow.h"
+#include "base/zlib_util_c.h"
 */
 import "C"
 
 import (
 	// "fmt"
 
+	"context"
+	"image"
 	"os"
 	"reflect"
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 	"unsafe"
 	// "syscall"
 
+	"github.com/go-vgo/robotgo/bitmap"
 	"github.com/go-vgo/robotgo/clipboard"
 	"github.com/shirou/gopsutil/process"
 )
@@ -77,15 +82,10 @@ type (
 	CBitmap C.MMBitmapRef
 )
 
-// Bitmap is Bitmap struct
-type Bitmap struct {
-	ImageBuffer   *uint8
-	Width         int
-	Height        int
-	Bytewidth     int
-	BitsPerPixel  uint8
-	BytesPerPixel uint8
-}
+// Bitmap is Bitmap struct, the Go owned bitmap(bitmap.Bitmap),
+// it implements image.Image; the ImageBuffer is a []uint8 copy of
+// the pixels, it was a *uint8 to the C memory before
+type Bitmap = bitmap.Bitmap
 
 // MPoint is MPoint struct
 type MPoint struct {
@@ -194,6 +194,89 @@ func GetScreenSize() (int, int) {
 	return int(size.width), int(size.height)
 }
 
+// maxDisplays is the max count of GetDisplays
+const maxDisplays = 32
+
+// Display is a display (monitor) of the virtual desktop
+type Display struct {
+	// ID the CGDirectDisplayID on macOS, the XRandR output on Linux
+	// (0 without XRandR 1.5), the index on Windows
+	ID int
+	// Name e.g. "DP-1" (XRandR) or "\\.\DISPLAY1" (Windows)
+	Name string
+	// Bounds the display rect in the virtual desktop coordinates,
+	// the points on macOS; the secondary displays may be negative
+	Bounds image.Rectangle
+	// Primary whether it is the main display
+	Primary bool
+	// Scale the pixels per point (macOS) or the dpi / 96, e.g. 2
+	Scale float64
+}
+
+// GetDisplays returns the displays, XRandR on Linux
+func GetDisplays() []Display {
+	var cds [maxDisplays]C.MMDisplay
+	n := int(C.get_displays(&cds[0], maxDisplays))
+
+	displays := make([]Display, n)
+	for i := 0; i < n; i++ {
+		b := cds[i].bounds
+		displays[i] = Display{
+			ID:      int(cds[i].id),
+			Name:    C.GoString(&cds[i].name[0]),
+			Bounds:  image.Rect(int(b.x), int(b.y), int(b.x+b.width), int(b.y+b.height)),
+			Primary: bool(cds[i].primary),
+			Scale:   float64(cds[i].scale),
+		}
+	}
+
+	return displays
+}
+
+// GetVirtualBounds returns the bounds of the virtual desktop,
+// the union of the displays
+func GetVirtualBounds() image.Rectangle {
+	var r image.Rectangle
+	for _, d := range GetDisplays() {
+		r = r.Union(d.Bounds)
+	}
+
+	return r
+}
+
+// CaptureVirtual capture the rect of the virtual desktop, the whole
+// virtual desktop (all displays) if rect is empty; the bitmap (0, 0)
+// is the rect.Min, return nil if the capture failed
+func CaptureVirtual(rect image.Rectangle) *Bitmap {
+	if rect.Empty() {
+		rect = GetVirtualBounds()
+	}
+	if rect.Empty() {
+		return nil
+	}
+
+	bit := C.capture_virtual(C.int(rect.Min.X), C.int(rect.Min.Y),
+		C.int(rect.Dx()), C.int(rect.Dy()))
+	if bit == nil {
+		return nil
+	}
+	defer FreeBitmap(bit)
+
+	return ToBitmap(bit)
+}
+
+// CaptureDisplay capture the display of the id, see GetDisplays;
+// return nil if the display is not found or the capture failed
+func CaptureDisplay(id int) *Bitmap {
+	for _, d := range GetDisplays() {
+		if d.ID == id {
+			return CaptureVirtual(d.Bounds)
+		}
+	}
+
+	return nil
+}
+
 // SetXDisplayName set XDisplay name
 func SetXDisplayName(name string) string {
 	cname := C.CString(name)
@@ -204,6 +287,19 @@ func SetXDisplayName(name string) string {
 	return gstr
 }
 
+// SetXShm enable or disable the MIT-SHM screen capture on Linux,
+// enabled by default; the capture falls back to XGetImage if the
+// shm is unavailable, e.g. a remote display
+func SetXShm(enable bool) {
+	C.set_xshm(C.bool(enable))
+}
+
+// HasXShm returns whether the MIT-SHM capture is enabled and
+// available, false on macOS and Windows
+func HasXShm() bool {
+	return bool(C.has_xshm())
+}
+
 // GetXDisplayName get XDisplay name
 func GetXDisplayName() string {
 	name := C.get_XDisplay_name()
@@ -213,7 +309,8 @@ func GetXDisplayName() string {
 	return gname
 }
 
-// CaptureScreen capture the screen return bitmap(c struct)
+// CaptureScreen capture the screen return bitmap(c struct),
+// the caller must free it with FreeBitmap; see Capture
 func CaptureScreen(args ...int) C.MMBitmapRef {
 	var (
 		x C.size_t
@@ -243,18 +340,119 @@ func CaptureScreen(args ...int) C.MMBitmapRef {
 	return bit
 }
 
-// GoCaptureScreen capture the screen and return bitmap(go struct)
-// func GoCaptureScreen(args ...int) Bitmap {
-// 	var bit C.MMBitmapRef
+// ToBitmap trans C.MMBitmapRef to Bitmap, copy the pixels to
+// the Go memory; return nil if the bitmap is nil or empty
+func ToBitmap(bit C.MMBitmapRef) *Bitmap {
+	if bit == nil || bit.imageBuffer == nil {
+		return nil
+	}
 
-// 	if len(args) > 3 {
-// 		bit = CaptureScreen(args[0], args[1], args[2], args[3])
-// 	} else {
-// 		bit = CaptureScreen()
-// 	}
+	size := C.int(bit.bytewidth * bit.height)
+	return bitmap.FromBuffer(C.GoBytes(unsafe.Pointer(bit.imageBuffer), size),
+		int(bit.width), int(bit.height), int(bit.bytewidth),
+		uint8(bit.bitsPerPixel))
+}
 
-// 	return ToBitmap(bit)
-// }
+// CaptureImage capture the screen and return the Go owned bitmap,
+// the C bitmap is freed, return nil if the capture failed
+//
+//	robotgo.CaptureImage(x, y, w, h int)
+func CaptureImage(args ...int) *Bitmap {
+	bit := CaptureScreen(args...)
+	if bit == nil {
+		return nil
+	}
+	defer FreeBitmap(bit)
+
+	return ToBitmap(bit)
+}
+
+// GoCaptureScreen capture the screen and return bitmap(go struct),
+// Equivalent to CaptureImage
+//
+// Deprecated: use CaptureImage, it returns nil if the capture failed.
+func GoCaptureScreen(args ...int) Bitmap {
+	bit := CaptureImage(args...)
+	if bit == nil {
+		return Bitmap{}
+	}
+
+	return *bit
+}
+
+// FreeBitmap free and dealloc the C bitmap of CaptureScreen,
+// the bitmap must not be used or freed again
+func FreeBitmap(bitmap C.MMBitmapRef) {
+	if bitmap != nil {
+		C.destroyMMBitmap(bitmap)
+	}
+}
+
+// CBitmapCount returns the count of the live C bitmaps, created and not
+// freed, including the temporary bitmaps of the C functions; e.g. a leak
+// test asserts the count is the same after the captures
+func CBitmapCount() int {
+	return int(C.liveMMBitmapCount())
+}
+
+// CaptureResult is the C bitmap of Capture, free it with Free or Close;
+// the finalizer frees it if it is not freed, as a safety net
+type CaptureResult struct {
+	mu  sync.Mutex
+	bit C.MMBitmapRef
+}
+
+// Capture capture the screen and return the C bitmap, nil if the
+// capture failed; free it with Free (or Close) when done
+//
+//	c := robotgo.Capture(x, y, w, h)
+//	defer c.Free()
+func Capture(args ...int) *CaptureResult {
+	bit := CaptureScreen(args...)
+	if bit == nil {
+		return nil
+	}
+
+	c := &CaptureResult{bit: bit}
+	runtime.SetFinalizer(c, (*CaptureResult).Free)
+
+	return c
+}
+
+// CBitmap returns the C bitmap, nil if freed; the result must be
+// kept alive (or freed later) while the C bitmap is used
+func (c *CaptureResult) CBitmap() C.MMBitmapRef {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.bit
+}
+
+// ToBitmap returns the Go owned copy of the bitmap, nil if freed
+func (c *CaptureResult) ToBitmap() *Bitmap {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return ToBitmap(c.bit)
+}
+
+// Free free the C bitmap, it is safe to call Free more than once
+func (c *CaptureResult) Free() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.bit != nil {
+		FreeBitmap(c.bit)
+		c.bit = nil
+	}
+	runtime.SetFinalizer(c, nil)
+}
+
+// Close free the C bitmap, as Free; it implements io.Closer
+func (c *CaptureResult) Close() error {
+	c.Free()
+	return nil
+}
 
 // BCaptureScreen capture the screen and return bitmap(go struct),
 // Wno-deprecated
@@ -678,6 +876,23 @@ func WriteAll(text string) {
 	clipboard.WriteAll(text)
 }
 
+// ReadImage read the image/png or image/bmp bitmap from clipboard,
+// only on Linux (xclip) now
+func ReadImage() (*Bitmap, error) {
+	img, err := clipboard.ReadImage()
+	if err != nil {
+		return nil, err
+	}
+
+	return bitmap.FromImage(img), nil
+}
+
+// WriteImage write the bitmap to clipboard as image/png,
+// only on Linux (xclip) now
+func WriteImage(bit image.Image) error {
+	return clipboard.WriteImage(bit)
+}
+
 // CharCodeAt char code at utf-8
 func CharCodeAt(s string, n int) rune {
 	i := 0
@@ -796,30 +1011,38 @@ func SetKeyboardDelay(delay int) {
 |______/  |__|     |__|     |__|  |__| /__/     \__\ | _|
 */
 
-//// ToBitmap trans C.MMBitmapRef to Bitmap
-//func ToBitmap(bit C.MMBitmapRef) Bitmap {
-//	bitmap := Bitmap{
-//		ImageBuffer:   (*uint8)(bit.imageBuffer),
-//		Width:         int(bit.width),
-//		Height:        int(bit.height),
-//		Bytewidth:     int(bit.bytewidth),
-//		BitsPerPixel:  uint8(bit.bitsPerPixel),
-//		BytesPerPixel: uint8(bit.bytesPerPixel),
-//	}
-
-//	return bitmap
-//}
-
 //// ToMMBitmapRef trans CBitmap to C.MMBitmapRef
 //func ToMMBitmapRef(bit CBitmap) C.MMBitmapRef {
 //	return C.MMBitmapRef(bit)
 //}
 
-//// TostringBitmap tostring bitmap to string
-//func TostringBitmap(bit C.MMBitmapRef) string {
-//	strBit := C.tostring_bitmap(bit)
-//	return C.GoString(strBit)
-//}
+// zlibCompress compress with the C zlib level 9, as zlib_compress of
+// createStringFromMMBitmap
+func zlibCompress(raw []byte) ([]byte, error) {
+	var clen C.size_t
+
+	out := C.zlib_compress((*C.uint8_t)(unsafe.Pointer(&raw[0])),
+		C.size_t(len(raw)), 9, &clen)
+	if out == nil {
+		return nil, bitmap.ErrStringCompress
+	}
+	defer C.free(unsafe.Pointer(out))
+
+	return C.GoBytes(unsafe.Pointer(out), C.int(clen)), nil
+}
+
+// TostringBitmap the bitmap to the "b<width>,<height>,<data>" string,
+// the C zlib data as the C createStringFromMMBitmap, the same string
+// but the last base64 chars (the C reads past the data);
+// return "" if the bitmap can not be encoded
+func TostringBitmap(bit image.Image) string {
+	str, err := bitmap.ToString(bit, zlibCompress)
+	if err != nil {
+		return ""
+	}
+
+	return str
+}
 
 //// TocharBitmap tostring bitmap to C.char
 //func TocharBitmap(bit C.MMBitmapRef) *C.char {
@@ -827,121 +1050,186 @@ func SetKeyboardDelay(delay int) {
 //	return strBit
 //}
 
-//func internalFindBitmap(bit, sbit C.MMBitmapRef, tolerance float64) (int, int) {
-//	pos := C.find_bitmap(bit, sbit, C.float(tolerance))
-//	// fmt.Println("pos----", pos)
-//	return int(pos.x), int(pos.y)
-//}
+// toFloat returns the number as float64, def if v is not a number
+func toFloat(v interface{}, def float64) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case float32:
+		return float64(n)
+	case int:
+		return float64(n)
+	case int8:
+		return float64(n)
+	case int16:
+		return float64(n)
+	case int32:
+		return float64(n)
+	case int64:
+		return float64(n)
+	case uint:
+		return float64(n)
+	case uint8:
+		return float64(n)
+	case uint16:
+		return float64(n)
+	case uint32:
+		return float64(n)
+	case uint64:
+		return float64(n)
+	}
 
-//// FindBitmap find the bitmap
-////
-////	robotgo.FindBitmap(bitmap, subbitamp C.MMBitmapRef, tolerance float64)
-////
-//func FindBitmap(args ...interface{}) (int, int) {
-//	var (
-//		bit       C.MMBitmapRef
-//		sbit      C.MMBitmapRef
-//		tolerance float64
-//	)
+	return def
+}
 
-//	bit = args[0].(C.MMBitmapRef)
+// toInt returns the number as int, def if v is not a number
+func toInt(v interface{}, def int) int {
+	if n, ok := v.(int); ok {
+		return n
+	}
 
-//	if len(args) > 1 {
-//		sbit = args[1].(C.MMBitmapRef)
-//	} else {
-//		sbit = CaptureScreen()
-//	}
+	return int(toFloat(v, float64(def)))
+}
 
-//	if len(args) > 2 {
-//		tolerance = args[2].(float64)
-//	} else {
-//		tolerance = 0.5
-//	}
+// findArgs parse the haystack and tolerance args,
+// capture the screen if the haystack is nil or omitted
+func findArgs(args []interface{}) (image.Image, float64) {
+	var (
+		sbit      image.Image
+		tolerance = 0.5
+	)
 
-//	return internalFindBitmap(bit, sbit, tolerance)
-//}
+	if len(args) > 0 {
+		// a typed nil *Bitmap is nil too, capture the screen for it
+		if img, ok := args[0].(image.Image); ok && img != nil {
+			if bit, ok := img.(*bitmap.Bitmap); !ok || bit != nil {
+				sbit = img
+			}
+		}
+	}
 
-//// FindPic finding the image by path
-////
-////	robotgo.FindPic(path string, subbitamp C.MMBitmapRef, tolerance float64)
-////
-//func FindPic(path string, args ...interface{}) (int, int) {
-//	var (
-//		sbit      C.MMBitmapRef
-//		tolerance float64
-//	)
+	if len(args) > 1 {
+		tolerance = toFloat(args[1], tolerance)
+	}
 
-//	openbit := OpenBitmap(path)
+	if sbit == nil {
+		if bit := CaptureImage(); bit != nil {
+			sbit = bit
+		}
+	}
 
-//	if len(args) > 0 {
-//		sbit = args[0].(C.MMBitmapRef)
-//	} else {
-//		sbit = CaptureScreen()
-//	}
+	return sbit, tolerance
+}
 
-//	if len(args) > 1 {
-//		tolerance = args[1].(float64)
-//	} else {
-//		tolerance = 0.5
-//	}
+// FindBitmap find the bitmap, return -1, -1 if not found
+//
+//	robotgo.FindBitmap(bitmap, subbitamp image.Image, tolerance float64)
+//
+// subbitamp is the haystack, the screen is captured if it is nil or omitted
+func FindBitmap(bit image.Image, args ...interface{}) (int, int) {
+	sbit, tolerance := findArgs(args)
+	if sbit == nil {
+		return -1, -1
+	}
 
-//	fx, fy := internalFindBitmap(openbit, sbit, tolerance)
+	res, ok := bitmap.Find(sbit, bit, &bitmap.Options{Tolerance: tolerance})
+	if !ok {
+		return -1, -1
+	}
 
-//	return fx, fy
-//}
+	return res.X, res.Y
+}
 
-//// FindEveryBitmap find the every bitmap
-//func FindEveryBitmap(args ...interface{}) (int, int) {
-//	var (
-//		bit       C.MMBitmapRef
-//		sbit      C.MMBitmapRef
-//		tolerance C.float
-//		lpos      C.MMPoint
-//	)
+// FindPic finding the image by path, return -1, -1 if not found
+//
+//	robotgo.FindPic(path string, subbitamp image.Image, tolerance float64)
+func FindPic(path string, args ...interface{}) (int, int) {
+	openbit, err := bitmap.OpenImage(path)
+	if err != nil {
+		return -1, -1
+	}
 
-//	bit = args[0].(C.MMBitmapRef)
-//	if len(args) > 1 {
-//		sbit = args[1].(C.MMBitmapRef)
-//	} else {
-//		sbit = CaptureScreen()
-//	}
+	return FindBitmap(openbit, args...)
+}
 
-//	if len(args) > 2 {
-//		tolerance = C.float(args[2].(float64))
-//	} else {
-//		tolerance = 0.5
-//	}
+// FindEveryBitmap find the every bitmap, the matches do not overlap
+//
+//	robotgo.FindEveryBitmap(bitmap, subbitamp image.Image, tolerance float64, max int)
+func FindEveryBitmap(bit image.Image, args ...interface{}) []bitmap.Result {
+	sbit, tolerance := findArgs(args)
+	if sbit == nil {
+		return nil
+	}
 
-//	if len(args) > 3 {
-//		lpos.x = C.size_t(args[3].(int))
-//		lpos.y = 0
-//	} else {
-//		lpos.x = 0
-//		lpos.y = 0
-//	}
+	opt := &bitmap.Options{Tolerance: tolerance}
+	if len(args) > 2 {
+		opt.Max = toInt(args[2], 0)
+	}
 
-//	if len(args) > 4 {
-//		lpos.x = C.size_t(args[3].(int))
-//		lpos.y = C.size_t(args[4].(int))
-//	}
+	return bitmap.FindAll(sbit, bit, opt)
+}
 
-//	pos := C.find_every_bitmap(bit, sbit, tolerance, &lpos)
-//	// fmt.Println("pos----", pos)
-//	return int(pos.x), int(pos.y)
-//}
+// CountBitmap count of the bitmap
+//
+//	robotgo.CountBitmap(bitmap, subbitamp image.Image, tolerance float64)
+func CountBitmap(bit image.Image, args ...interface{}) int {
+	return len(FindEveryBitmap(bit, args...))
+}
 
-//// CountBitmap count of the bitmap
-//func CountBitmap(bitmap C.MMBitmapRef, sbit C.MMBitmapRef, args ...float32) int {
-//	var tolerance C.float
-//	if len(args) > 0 {
-//		tolerance = C.float(args[0])
-//	} else {
-//		tolerance = 0.5
-//	}
+// captureRect capture the rect of the screen, the whole screen
+// if rect is empty, return the bitmap and its screen origin
+func captureRect(rect image.Rectangle) (*Bitmap, image.Point) {
+	if rect.Empty() {
+		return CaptureImage(), image.Point{}
+	}
 
-//	count := C.count_of_bitmap(bitmap, sbit, tolerance)
-//	return int(count)
-//}
+	return CaptureImage(rect.Min.X, rect.Min.Y, rect.Dx(), rect.Dy()), rect.Min
+}
+
+// FindImage capture the screen and find the needle,
+// only opt.Rect of the screen is captured if it is not empty,
+// the result is in the screen coordinates
+func FindImage(needle image.Image, opt *bitmap.Options) (bitmap.Result, bool) {
+	var o bitmap.Options
+	if opt != nil {
+		o = *opt
+	}
+
+	sbit, origin := captureRect(o.Rect)
+	if sbit == nil {
+		return bitmap.Result{}, false
+	}
+	o.Rect = image.Rectangle{}
+
+	res, ok := bitmap.Find(sbit, needle, &o)
+	res.X += origin.X
+	res.Y += origin.Y
+
+	return res, ok
+}
+
+// FindEveryImage capture the screen and find every needle,
+// see FindImage
+func FindEveryImage(needle image.Image, opt *bitmap.Options) []bitmap.Result {
+	var o bitmap.Options
+	if opt != nil {
+		o = *opt
+	}
+
+	sbit, origin := captureRect(o.Rect)
+	if sbit == nil {
+		return nil
+	}
+	o.Rect = image.Rectangle{}
+
+	res := bitmap.FindAll(sbit, needle, &o)
+	for i := 0; i < len(res); i++ {
+		res[i].X += origin.X
+		res[i].Y += origin.Y
+	}
+
+	return res
+}
 
 //// FindBit find the bitmap, Wno-deprecated
 //func FindBit(args ...interface{}) (int, int) {
@@ -983,24 +1271,11 @@ func SetKeyboardDelay(delay int) {
 //	return bool(cbool)
 //}
 
-//// OpenBitmap open the bitmap
-//func OpenBitmap(args ...interface{}) C.MMBitmapRef {
-//	path := C.CString(args[0].(string))
-//	var mtype C.uint16_t
-
-//	Try(func() {
-//		mtype = C.uint16_t(args[1].(int))
-//	}, func(e interface{}) {
-//		// fmt.Println("err:::", e)
-//		mtype = 1
-//	})
-
-//	bit := C.bitmap_open(path, mtype)
-//	defer C.free(unsafe.Pointer(path))
-//	// fmt.Println("opening...", bit)
-//	return bit
-//	// defer C.free(unsafe.Pointer(path))
-//}
+// OpenBitmap open the bitmap, the png, bmp, jpeg, gif,
+// tiff, ppm, pgm and pam image are supported
+func OpenBitmap(path string) (*Bitmap, error) {
+	return bitmap.Open(path)
+}
 
 //// DecodeImg decode the image to image.Image and return
 //func DecodeImg(path string) (image.Image, string, error) {
@@ -1012,33 +1287,31 @@ func SetKeyboardDelay(delay int) {
 //	return imgo.ImgToBytes(path)
 //}
 
-//// BitmapStr bitmap from string
-//func BitmapStr(str string) C.MMBitmapRef {
-//	cs := C.CString(str)
-//	bit := C.bitmap_from_string(cs)
-//	defer C.free(unsafe.Pointer(cs))
-
-//	return bit
-//}
+// BitmapStr the bitmap from the "b<width>,<height>,<data>" string
+// of TostringBitmap, the errors are the MMBMPStringError errors
+func BitmapStr(str string) (*Bitmap, error) {
+	return bitmap.FromString(str)
+}
 
-//// SaveBitmap save the bitmap
-//func SaveBitmap(args ...interface{}) string {
-//	var mtype C.uint16_t
-//	Try(func() {
-//		mtype = C.uint16_t(args[2].(int))
-//	}, func(e interface{}) {
-//		// fmt.Println("err:::", e)
-//		mtype = 1
-//	})
+// SaveBitmap save the bitmap, the image type is selected by
+// the file extension if mtype is omitted, quality is the jpeg quality
+//
+//	robotgo.SaveBitmap(bitmap image.Image, path string, mtype, quality int)
+func SaveBitmap(bit image.Image, path string, args ...int) error {
+	if len(args) == 0 {
+		return bitmap.Save(bit, path)
+	}
+	if args[0] == int(bitmap.InvalidImageType) {
+		return bitmap.ErrUnsupportedType
+	}
 
-//	path := C.CString(args[1].(string))
-//	savebit := C.bitmap_save(args[0].(C.MMBitmapRef), path, mtype)
-//	// fmt.Println("saved...", savebit)
-//	// return bit
-//	defer C.free(unsafe.Pointer(path))
+	opt := &bitmap.SaveOptions{Type: bitmap.ImageType(args[0])}
+	if len(args) > 1 {
+		opt.Quality = args[1]
+	}
 
-//	return C.GoString(savebit)
-//}
+	return bitmap.SaveWith(bit, path, opt)
+}
 
 // func SaveBitmap(bit C.MMBitmapRef, gpath string, mtype C.MMImageType) {
 // 	path := C.CString(gpath)
@@ -1048,17 +1321,11 @@ func SetKeyboardDelay(delay int) {
 // 	// defer C.free(unsafe.Pointer(path))
 // }
 
-// GetPortion get bitmap portion
-// func GetPortion(bit C.MMBitmapRef, x, y, w, h int) C.MMBitmapRef {
-// 	var rect C.MMRect
-// 	rect.origin.x = C.size_t(x)
-// 	rect.origin.y = C.size_t(y)
-// 	rect.size.width = C.size_t(w)
-// 	rect.size.height = C.size_t(h)
-
-// 	pos := C.get_portion(bit, rect)
-// 	return pos
-// }
+// GetPortion get the bitmap portion x, y, w, h, see bitmap.Crop;
+// nil if the portion is out of the bitmap
+func GetPortion(bit image.Image, x, y, w, h int) *Bitmap {
+	return bitmap.Crop(bit, image.Rect(x, y, x+w, y+h))
+}
 
 // // Convert convert bitmap
 // func Convert(args ...interface{}) {
@@ -1078,12 +1345,6 @@ func SetKeyboardDelay(delay int) {
 // 	SaveBitmap(bitmap, spath, mtype)
 // }
 
-// // FreeBitmap free and dealloc bitmap
-// func FreeBitmap(bitmap C.MMBitmapRef) {
-// 	// C.destroyMMBitmap(bitmap)
-// 	C.bitmap_dealloc(bitmap)
-// }
-
 // // ReadBitmap returns false and sets error if |bitmap| is NULL
 // func ReadBitmap(bitmap C.MMBitmapRef) bool {
 // 	abool := C.bitmap_ready(bitmap)
@@ -1111,68 +1372,246 @@ func SetKeyboardDelay(delay int) {
 // 	return color
 // }
 
-// // FindColor find bitmap color
-// func FindColor(bitmap C.MMBitmapRef, color CHex, args ...float32) (int, int) {
-// 	var tolerance C.float
+// FindColor find the color, return -1, -1 if not found
+//
+//	robotgo.FindColor(color CHex, bitmap image.Image, tolerance float64)
+//
+// the screen is captured if the bitmap is nil or omitted
+func FindColor(color CHex, args ...interface{}) (int, int) {
+	sbit, tolerance := findArgs(args)
+	if sbit == nil {
+		return -1, -1
+	}
 
-// 	if len(args) > 0 {
-// 		tolerance = C.float(args[0])
-// 	} else {
-// 		tolerance = 0.5
-// 	}
+	pos, ok := bitmap.FindColor(sbit, uint32(color),
+		&bitmap.ColorOptions{Tolerance: tolerance})
+	if !ok {
+		return -1, -1
+	}
 
-// 	pos := C.bitmap_find_color(bitmap, C.MMRGBHex(color), tolerance)
-// 	x := int(pos.x)
-// 	y := int(pos.y)
+	return pos.X, pos.Y
+}
 
-// 	return x, y
-// }
+// FindColorCS capture the screen rect and find the color,
+// return the screen point, -1, -1 if not found
+func FindColorCS(x, y, w, h int, color CHex, args ...float64) (int, int) {
+	tolerance := 0.5
+	if len(args) > 0 {
+		tolerance = args[0]
+	}
 
-// // FindColorCS findcolor by CaptureScreen
-// func FindColorCS(x, y, w, h int, color CHex, args ...float32) (int, int) {
-// 	var tolerance float32
+	bit := CaptureImage(x, y, w, h)
+	if bit == nil {
+		return -1, -1
+	}
 
-// 	if len(args) > 0 {
-// 		tolerance = args[0]
-// 	} else {
-// 		tolerance = 0.5
-// 	}
+	rx, ry := FindColor(color, bit, tolerance)
+	if rx < 0 {
+		return -1, -1
+	}
 
-// 	bitmap := CaptureScreen(x, y, w, h)
-// 	rx, ry := FindColor(bitmap, color, tolerance)
-// 	return rx, ry
-// }
+	return rx + x, ry + y
+}
 
-// // CountColor count bitmap color
-// func CountColor(bitmap C.MMBitmapRef, color CHex, args ...float32) int {
-// 	var tolerance C.float
+// CountColor count of the color
+//
+//	robotgo.CountColor(color CHex, bitmap image.Image, tolerance float64)
+func CountColor(color CHex, args ...interface{}) int {
+	sbit, tolerance := findArgs(args)
+	if sbit == nil {
+		return 0
+	}
 
-// 	if len(args) > 0 {
-// 		tolerance = C.float(args[0])
-// 	} else {
-// 		tolerance = 0.5
-// 	}
+	return bitmap.CountColor(sbit, uint32(color),
+		&bitmap.ColorOptions{Tolerance: tolerance})
+}
 
-// 	count := C.bitmap_count_of_color(bitmap, C.MMRGBHex(color), tolerance)
+// CountColorCS capture the screen rect and count of the color
+func CountColorCS(x, y, w, h int, color CHex, args ...float64) int {
+	tolerance := 0.5
+	if len(args) > 0 {
+		tolerance = args[0]
+	}
 
-// 	return int(count)
-// }
+	bit := CaptureImage(x, y, w, h)
+	if bit == nil {
+		return 0
+	}
 
-// // CountColorCS count bitmap color by CaptureScreen
-// func CountColorCS(x, y, w, h int, color CHex, args ...float32) int {
-// 	var tolerance float32
+	return CountColor(color, bit, tolerance)
+}
 
-// 	if len(args) > 0 {
-// 		tolerance = args[0]
-// 	} else {
-// 		tolerance = 0.5
-// 	}
+// FindEveryColor capture the screen and find every pixel of the color,
+// only opt.Rect of the screen is captured if it is not empty,
+// the points are in the screen coordinates
+func FindEveryColor(color CHex, opt *bitmap.ColorOptions) []image.Point {
+	var o bitmap.ColorOptions
+	if opt != nil {
+		o = *opt
+	}
+
+	sbit, origin := captureRect(o.Rect)
+	if sbit == nil {
+		return nil
+	}
+	o.Rect = image.Rectangle{}
 
-// 	bitmap := CaptureScreen(x, y, w, h)
-// 	rx := CountColor(bitmap, color, tolerance)
+	points := bitmap.FindAllColor(sbit, uint32(color), &o)
+	for i := 0; i < len(points); i++ {
+		points[i] = points[i].Add(origin)
+	}
 
-// 	return rx
-// }
+	return points
+}
+
+// FindColorBlobs capture the screen and group the pixels of the color
+// into the connected blobs, see FindEveryColor
+func FindColorBlobs(color CHex, opt *bitmap.ColorOptions) []bitmap.Blob {
+	var o bitmap.ColorOptions
+	if opt != nil {
+		o = *opt
+	}
+
+	sbit, origin := captureRect(o.Rect)
+	if sbit == nil {
+		return nil
+	}
+	o.Rect = image.Rectangle{}
+
+	blobs := bitmap.FindBlobs(sbit, uint32(color), &o)
+	for i := 0; i < len(blobs); i++ {
+		blobs[i].Rect = blobs[i].Rect.Add(origin)
+		blobs[i].CX += float64(origin.X)
+		blobs[i].CY += float64(origin.Y)
+	}
+
+	return blobs
+}
+
+// MatchSignature whether the signature matches at the screen point x, y,
+// only the signature bounds of the screen are captured
+func MatchSignature(x, y int, sig bitmap.Signature, opt *bitmap.ColorOptions) bool {
+	r := sig.Bounds().Add(image.Pt(x, y))
+	if r.Min.X < 0 || r.Min.Y < 0 {
+		return false
+	}
+
+	bit := CaptureImage(r.Min.X, r.Min.Y, r.Dx(), r.Dy())
+	if bit == nil {
+		return false
+	}
+
+	return bitmap.MatchSignature(bit, sig, image.Pt(x, y).Sub(r.Min), opt)
+}
+
+// FindSignature capture the screen and find the first anchor point
+// of the signature, return -1, -1 if not found; see FindEverySignature
+func FindSignature(sig bitmap.Signature, opt *bitmap.ColorOptions) (int, int) {
+	var o bitmap.ColorOptions
+	if opt != nil {
+		o = *opt
+	}
+
+	sbit, origin := captureRect(o.Rect)
+	if sbit == nil {
+		return -1, -1
+	}
+	o.Rect = image.Rectangle{}
+
+	pos, ok := bitmap.FindSignature(sbit, sig, &o)
+	if !ok {
+		return -1, -1
+	}
+
+	return pos.X + origin.X, pos.Y + origin.Y
+}
+
+// FindEverySignature capture the screen and find every anchor point
+// of the signature, only opt.Rect of the screen is captured if it is
+// not empty, the points are in the screen coordinates
+func FindEverySignature(sig bitmap.Signature, opt *bitmap.ColorOptions) []image.Point {
+	var o bitmap.ColorOptions
+	if opt != nil {
+		o = *opt
+	}
+
+	sbit, origin := captureRect(o.Rect)
+	if sbit == nil {
+		return nil
+	}
+	o.Rect = image.Rectangle{}
+
+	points := bitmap.FindAllSignature(sbit, sig, &o)
+	for i := 0; i < len(points); i++ {
+		points[i] = points[i].Add(origin)
+	}
+
+	return points
+}
+
+// HashScreen capture the rect of the screen and returns its perceptual
+// hash, the whole screen if rect is empty; false if the capture fails
+//
+//	h, _ := robotgo.HashScreen(rect, bitmap.HashPerceptual)
+//	same := h.Distance(saved) <= 10
+func HashScreen(rect image.Rectangle, t bitmap.HashType) (bitmap.Hash, bool) {
+	sbit, _ := captureRect(rect)
+	if sbit == nil {
+		return 0, false
+	}
+
+	return bitmap.ImageHash(sbit, t), true
+}
+
+// GetHistogram capture the rect of the screen and returns its color
+// histogram, the whole screen if rect is empty; nil if the capture fails
+//
+//	h := robotgo.GetHistogram(rect)
+//	mean, median := h.Mean(), h.Median()
+func GetHistogram(rect image.Rectangle) *bitmap.Histogram {
+	sbit, _ := captureRect(rect)
+	if sbit == nil {
+		return nil
+	}
+
+	return bitmap.NewHistogram(sbit)
+}
+
+// GetDominantColors capture the rect of the screen and returns its n
+// dominant colors, the largest first, see bitmap.DominantColors
+func GetDominantColors(rect image.Rectangle, n int) []bitmap.DominantColor {
+	sbit, _ := captureRect(rect)
+	if sbit == nil {
+		return nil
+	}
+
+	return bitmap.DominantColors(sbit, n)
+}
+
+// ReadText capture the rect of the screen and read its text with the
+// glyph recognizer, the whole screen if rect is empty; the char rects
+// are in the screen coordinates, see bitmap.Recognizer; return ""
+// if r is nil or the capture failed
+//
+//	r, err := bitmap.LoadRecognizer("glyphs", nil)
+//	text, chars := robotgo.ReadText(image.Rect(10, 10, 90, 30), r)
+func ReadText(rect image.Rectangle, r *bitmap.Recognizer) (string, []bitmap.Char) {
+	if r == nil {
+		return "", nil
+	}
+
+	sbit, origin := captureRect(rect)
+	if sbit == nil {
+		return "", nil
+	}
+
+	text, chars := r.Recognize(sbit)
+	for i := range chars {
+		chars[i].Rect = chars[i].Rect.Add(origin)
+	}
+
+	return text, chars
+}
 
 // // GetImgSize get the image size
 // func GetImgSize(imgPath string) (int, int) {
@@ -1185,6 +1624,265 @@ func SetKeyboardDelay(delay int) {
 // 	return w, h
 // }
 
+// defaultWaitInterval is the poll interval of the WaitFor functions
+const defaultWaitInterval = 100 * time.Millisecond
+
+// WaitOptions is the poll options of the WaitFor functions
+type WaitOptions struct {
+	// Interval the poll interval, default 100ms
+	Interval time.Duration
+	// Timeout the max wait time, 0 waits until the ctx is done;
+	// context.DeadlineExceeded is returned on the timeout
+	Timeout time.Duration
+}
+
+// waitPoll call check every poll interval until it returns true,
+// return nil, or the ctx error if the ctx is done or timeout
+func waitPoll(ctx context.Context, wopt *WaitOptions, check func() bool) error {
+	interval := defaultWaitInterval
+	if wopt != nil {
+		if wopt.Interval > 0 {
+			interval = wopt.Interval
+		}
+		if wopt.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, wopt.Timeout)
+			defer cancel()
+		}
+	}
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		// select picks randomly if the ctx is done as the timer fires
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if check() {
+			return nil
+		}
+		timer.Reset(interval)
+	}
+}
+
+// WaitForImage wait until the needle appears on the screen,
+// see FindImage; return the match, or the ctx error
+//
+//	robotgo.WaitForImage(ctx, bit, &bitmap.Options{Tolerance: 0.1},
+//		&robotgo.WaitOptions{Timeout: 10 * time.Second})
+func WaitForImage(ctx context.Context, needle image.Image, opt *bitmap.Options,
+	wopt *WaitOptions) (bitmap.Result, error) {
+	var res bitmap.Result
+
+	err := waitPoll(ctx, wopt, func() bool {
+		var ok bool
+		res, ok = FindImage(needle, opt)
+		return ok
+	})
+
+	return res, err
+}
+
+// WaitForImageGone wait until the needle is not on the screen,
+// see FindImage
+func WaitForImageGone(ctx context.Context, needle image.Image,
+	opt *bitmap.Options, wopt *WaitOptions) error {
+	var o bitmap.Options
+	if opt != nil {
+		o = *opt
+	}
+	rect := o.Rect
+	o.Rect = image.Rectangle{}
+
+	return waitPoll(ctx, wopt, func() bool {
+		// not gone if the screen can not be captured
+		sbit, _ := captureRect(rect)
+		if sbit == nil {
+			return false
+		}
+
+		_, ok := bitmap.Find(sbit, needle, &o)
+		return !ok
+	})
+}
+
+// WaitForColor wait until the screen pixel x, y is the color,
+// within the tolerance (0.0 - 1.0, see bitmap.Similar)
+func WaitForColor(ctx context.Context, x, y int, color CHex, tolerance float64,
+	wopt *WaitOptions) error {
+	return waitPoll(ctx, wopt, func() bool {
+		bit := CaptureImage(x, y, 1, 1)
+		if bit == nil {
+			return false
+		}
+
+		return bitmap.Similar(bit.HexAt(0, 0), uint32(color), tolerance)
+	})
+}
+
+// WaitForStable wait until the screen region does not change for
+// the quiet period, the zero region is the whole screen;
+// return the last capture, or the ctx error
+func WaitForStable(ctx context.Context, region image.Rectangle,
+	quiet time.Duration, wopt *WaitOptions) (*Bitmap, error) {
+	var (
+		prev    *Bitmap
+		changed time.Time
+	)
+
+	err := waitPoll(ctx, wopt, func() bool {
+		cur, _ := captureRect(region)
+		if cur == nil {
+			return false
+		}
+
+		now := time.Now()
+		if prev == nil {
+			prev, changed = cur, now
+			return quiet <= 0
+		}
+
+		same := bitmap.Equal(prev, cur)
+		prev = cur
+		if !same {
+			changed = now
+			return false
+		}
+
+		return now.Sub(changed) >= quiet
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return prev, nil
+}
+
+// defaultRecordFPS is the default capture rate of the Recorder
+const defaultRecordFPS = 10
+
+// RecordOptions is the screen recording options
+type RecordOptions struct {
+	// Rect the recorded screen rect, the zero Rect is the whole screen
+	Rect image.Rectangle
+	// FPS the capture rate, default 10
+	FPS float64
+	// MaxDuration the recording is stopped after MaxDuration,
+	// 0 records until Stop
+	MaxDuration time.Duration
+	// Video the video type, the AVI quality and the frame
+	// dedup; Video.FPS is FPS
+	Video bitmap.VideoOptions
+}
+
+// Recorder records the screen to a video, see StartRecord
+type Recorder struct {
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+	err  error
+}
+
+// StartRecord start recording the screen to the animated gif, the
+// animated png or the mjpeg avi file, the type is selected by the file
+// extension if opt.Video.Type is omitted; the frames are captured until
+// Stop or opt.MaxDuration, the delays are the capture times
+//
+//	rec, err := robotgo.StartRecord("run.gif", &robotgo.RecordOptions{
+//		FPS: 5, Video: bitmap.VideoOptions{Dedup: true}})
+//	...
+//	err = rec.Stop()
+func StartRecord(path string, opt *RecordOptions) (*Recorder, error) {
+	var o RecordOptions
+	if opt != nil {
+		o = *opt
+	}
+	if o.FPS <= 0 {
+		o.FPS = defaultRecordFPS
+	}
+	o.Video.FPS = o.FPS
+
+	w, err := bitmap.CreateVideo(path, &o.Video)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Recorder{stop: make(chan struct{}), done: make(chan struct{})}
+	go r.run(w, &o)
+
+	return r, nil
+}
+
+// run capture the frames until stop or the max duration,
+// a frame is written on the next capture, when its delay is known
+func (r *Recorder) run(w bitmap.VideoWriter, opt *RecordOptions) {
+	defer close(r.done)
+
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / opt.FPS))
+	defer ticker.Stop()
+
+	var timeout <-chan time.Time
+	if opt.MaxDuration > 0 {
+		timer := time.NewTimer(opt.MaxDuration)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var (
+		prev *Bitmap
+		last time.Time
+		err  error
+	)
+	for err == nil {
+		// a failed capture is skipped, the previous frame is longer
+		if cur, _ := captureRect(opt.Rect); cur != nil {
+			now := time.Now()
+			if prev != nil {
+				err = w.WriteFrame(prev, now.Sub(last))
+			}
+			prev, last = cur, now
+		}
+
+		select {
+		case <-r.stop:
+		case <-timeout:
+		case <-ticker.C:
+			continue
+		}
+		break
+	}
+
+	if err == nil && prev != nil {
+		err = w.WriteFrame(prev, time.Since(last))
+	}
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	r.err = err
+}
+
+// Stop stop the recording and finish the video, return the error
+// of the recording
+func (r *Recorder) Stop() error {
+	r.once.Do(func() { close(r.stop) })
+	<-r.done
+
+	return r.err
+}
+
+// Done returns a channel that is closed when the recording is
+// finished, by Stop, opt.MaxDuration or an error
+func (r *Recorder) Done() <-chan struct{} {
+	return r.done
+}
+
 /*
  ___________    ____  _______ .__   __. .___________.
 |   ____\   \  /   / |   ____||  \ |  | |           |
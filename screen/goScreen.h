@@ -45,48 +45,43 @@ uint32_t color_rgb_to_hex(uint8_t r, uint8_t g, uint8_t b){
 	return RGB_TO_HEX(r, g, b);
 }
 
-MMRGBHex get_px_color(size_t x, size_t y){
+// read_px_color read the pixel, the point is checked by the caller
+static MMRGBHex read_px_color(size_t x, size_t y){
 	MMBitmapRef bitmap;
-	MMRGBHex color;
+	MMRGBHex color = 0;
 
-	if (!pointVisibleOnMainDisplay(MMPointMake(x, y))){
+	bitmap = copyMMBitmapFromVirtualRect(MMSignedRectMake(x, y, 1, 1));
+	if (bitmap == NULL) {
 		return color;
 	}
 
-	bitmap = copyMMBitmapFromDisplayInRect(MMRectMake(x, y, 1, 1));
-	// bitmap = MMRectMake(x, y, 1, 1);
-
 	color = MMRGBHexAtPoint(bitmap, 0, 0);
+	destroyMMBitmap(bitmap);
 
 	return color;
 }
 
-char* get_pixel_color(size_t x, size_t y){
-	MMBitmapRef bitmap;
-	MMRGBHex color;
-
-	if (!pointVisibleOnMainDisplay(MMPointMake(x, y))){
-		// return 1;
-		return "screen's dimensions.";
+MMRGBHex get_px_color(size_t x, size_t y){
+	if (!pointVisibleOnDisplays(MMPointMake(x, y))){
+		return 0;
 	}
 
-	bitmap = copyMMBitmapFromDisplayInRect(MMRectMake(x, y, 1, 1));
-	// bitmap = MMRectMake(x, y, 1, 1);
-
-	color = MMRGBHexAtPoint(bitmap, 0, 0);
-
-	char hex[7];
-
-	padHex(color, hex);
-
-	destroyMMBitmap(bitmap);
+	return read_px_color(x, y);
+}
 
-	// printf("%s\n", hex);
+char* get_pixel_color(size_t x, size_t y){
+	// the caller frees the string
+	char* s = (char*)calloc(100, sizeof(char*));
+	if (s == NULL) {
+		return NULL;
+	}
 
-	// return 0;
+	if (!pointVisibleOnDisplays(MMPointMake(x, y))){
+		strcpy(s, "screen's dimensions.");
+		return s;
+	}
 
-	char* s = (char*)calloc(100, sizeof(char*));
-    if(s)strcpy(s, hex);
+	padHex(read_px_color(x, y), s);
 
 	return s;
 }
@@ -118,6 +113,16 @@ char* get_XDisplay_name(){
 	#endif
 }
 
+// set_xshm enable or disable the MIT-SHM capture
+void set_xshm(bool enabled){
+	setXShmEnabled(enabled);
+}
+
+// has_xshm whether the MIT-SHM capture is available
+bool has_xshm(){
+	return xShmAvailable();
+}
+
 // capture_screen capture screen
 MMBitmapRef capture_screen(size_t x, size_t y, size_t w, size_t h){
 	// if (){
@@ -135,3 +140,12 @@ MMBitmapRef capture_screen(size_t x, size_t y, size_t w, size_t h){
 	return bitmap;
 }
 
+// capture_virtual capture the rect of the virtual desktop (all displays)
+MMBitmapRef capture_virtual(int x, int y, int w, int h){
+	return copyMMBitmapFromVirtualRect(MMSignedRectMake(x, y, w, h));
+}
+
+// get_displays fill at most max displays, return the count
+size_t get_displays(MMDisplay *displays, size_t max){
+	return getDisplays(displays, max);
+}
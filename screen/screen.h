@@ -15,6 +15,24 @@ extern "C"
 {
 #endif
 
+/* A display (monitor) of the virtual desktop. */
+struct _MMDisplay {
+	/* The CGDirectDisplayID on macOS, the XRandR output on Linux (0 without
+	 * XRandR 1.5), the index on Windows. */
+	unsigned int id;
+	char name[64];
+	/* The bounds in the virtual desktop coordinates, the points on macOS. */
+	MMSignedRect bounds;
+	bool primary;
+	/* The pixels per point (macOS) or the dpi / 96. */
+	double scale;
+};
+
+typedef struct _MMDisplay MMDisplay;
+
+/* Fills at most max displays, returns the count of the displays filled. */
+size_t getDisplays(MMDisplay *displays, size_t max);
+
 /* Returns the size of the main display. */
 MMSize getMainDisplaySize(void);
 
@@ -22,6 +40,10 @@ MMSize getMainDisplaySize(void);
  * of the main screen. */
 bool pointVisibleOnMainDisplay(MMPoint point);
 
+/* Returns whether the given point is in the bounds of any display, without
+ * enumerating the displays; on X11 whether it is in the root window. */
+bool pointVisibleOnDisplays(MMPoint point);
+
 #ifdef __cplusplus
 }
 #endif
@@ -5,8 +5,12 @@
 	#include <ApplicationServices/ApplicationServices.h>
 #elif defined(USE_X11)
 	#include <X11/Xlib.h>
+	#include <X11/extensions/Xrandr.h>
 	// #include "../base/xdisplay_c.h"
 #endif
+#include <stdio.h> /* snprintf() */
+#include <stdlib.h> /* atof() */
+#include <string.h>
 
 MMSize getMainDisplaySize(void){
 #if defined(IS_MACOSX)
@@ -29,3 +33,173 @@ bool pointVisibleOnMainDisplay(MMPoint point){
 	MMSize displaySize = getMainDisplaySize();
 	return point.x < displaySize.width && point.y < displaySize.height;
 }
+
+#if defined(USE_X11)
+/* Returns the Xft.dpi / 96 of the X resources, or 1. */
+static double xftScale(Display *display){
+	char *res = XResourceManagerString(display);
+	char *dpi = res != NULL ? strstr(res, "Xft.dpi:") : NULL;
+	double v = dpi != NULL ? atof(dpi + strlen("Xft.dpi:")) : 0;
+
+	return v > 0 ? v / 96 : 1;
+}
+#elif defined(IS_WINDOWS)
+struct _MMDisplayList {
+	MMDisplay *displays;
+	size_t max;
+	size_t count;
+};
+
+static BOOL CALLBACK enumDisplay(HMONITOR monitor, HDC hdc, LPRECT rect,
+                                 LPARAM data){
+	struct _MMDisplayList *list = (struct _MMDisplayList *)data;
+	MONITORINFOEXA info;
+	MMDisplay *display;
+	HDC dc;
+
+	if (list->count >= list->max) return FALSE;
+
+	info.cbSize = sizeof(info);
+	if (!GetMonitorInfoA(monitor, (LPMONITORINFO)&info)) return TRUE;
+
+	display = &list->displays[list->count];
+	display->id = (unsigned int)list->count;
+	snprintf(display->name, sizeof(display->name), "%s", info.szDevice);
+	display->bounds = MMSignedRectMake(info.rcMonitor.left, info.rcMonitor.top,
+		info.rcMonitor.right - info.rcMonitor.left,
+		info.rcMonitor.bottom - info.rcMonitor.top);
+	display->primary = (info.dwFlags & MONITORINFOF_PRIMARY) != 0;
+
+	display->scale = 1;
+	dc = CreateDCA(info.szDevice, NULL, NULL, NULL);
+	if (dc != NULL) {
+		display->scale = GetDeviceCaps(dc, LOGPIXELSX) / 96.0;
+		DeleteDC(dc);
+	}
+
+	list->count++;
+	return TRUE;
+}
+#endif
+
+size_t getDisplays(MMDisplay *displays, size_t max){
+	if (max == 0) return 0;
+#if defined(IS_MACOSX)
+	CGDirectDisplayID ids[32];
+	uint32_t count = 0, i;
+
+	if (CGGetActiveDisplayList(max < 32 ? (uint32_t)max : 32, ids, &count)
+	    != kCGErrorSuccess) {
+		return 0;
+	}
+
+	for (i = 0; i < count; i++) {
+		CGRect b = CGDisplayBounds(ids[i]);
+		CGDisplayModeRef mode = CGDisplayCopyDisplayMode(ids[i]);
+
+		displays[i].id = ids[i];
+		snprintf(displays[i].name, sizeof(displays[i].name), "Display %u", ids[i]);
+		displays[i].bounds = MMSignedRectMake((int)b.origin.x, (int)b.origin.y,
+			(int)b.size.width, (int)b.size.height);
+		displays[i].primary = CGDisplayIsMain(ids[i]);
+
+		displays[i].scale = 1;
+		if (mode != NULL) {
+			if (CGDisplayModeGetWidth(mode) > 0) {
+				displays[i].scale = (double)CGDisplayModeGetPixelWidth(mode) /
+				                    CGDisplayModeGetWidth(mode);
+			}
+			CGDisplayModeRelease(mode);
+		}
+	}
+
+	return count;
+#elif defined(USE_X11)
+	Display *display = XGetMainDisplay();
+	int event, error, major = 0, minor = 0, n = 0, i;
+	size_t count = 0;
+	double scale;
+
+	if (display == NULL) return 0;
+	scale = xftScale(display);
+
+	/* The monitors need XRandR 1.5. */
+	if (XRRQueryExtension(display, &event, &error) &&
+	    XRRQueryVersion(display, &major, &minor) &&
+	    (major > 1 || (major == 1 && minor >= 5))) {
+		XRRMonitorInfo *monitors = XRRGetMonitors(display,
+			XDefaultRootWindow(display), True, &n);
+
+		for (i = 0; monitors != NULL && i < n && count < max; i++) {
+			MMDisplay *d = &displays[count++];
+			char *name = XGetAtomName(display, monitors[i].name);
+
+			d->id = monitors[i].noutput > 0 ? (unsigned int)monitors[i].outputs[0] : 0;
+			snprintf(d->name, sizeof(d->name), "%s", name != NULL ? name : "");
+			d->bounds = MMSignedRectMake(monitors[i].x, monitors[i].y,
+				monitors[i].width, monitors[i].height);
+			d->primary = monitors[i].primary;
+			d->scale = scale;
+
+			if (name != NULL) XFree(name);
+		}
+		if (monitors != NULL) XRRFreeMonitors(monitors);
+	}
+
+	if (count == 0) {
+		/* The one screen. */
+		const int screen = DefaultScreen(display);
+
+		displays[0].id = 0;
+		snprintf(displays[0].name, sizeof(displays[0].name), "%s",
+			DisplayString(display));
+		displays[0].bounds = MMSignedRectMake(0, 0,
+			DisplayWidth(display, screen), DisplayHeight(display, screen));
+		displays[0].primary = true;
+		displays[0].scale = scale;
+		count = 1;
+	}
+
+	return count;
+#elif defined(IS_WINDOWS)
+	struct _MMDisplayList list;
+	list.displays = displays;
+	list.max = max;
+	list.count = 0;
+
+	EnumDisplayMonitors(NULL, NULL, enumDisplay, (LPARAM)&list);
+	return list.count;
+#endif
+}
+
+bool pointVisibleOnDisplays(MMPoint point){
+	/* It is called per pixel read, the displays are not enumerated. */
+#if defined(IS_MACOSX)
+	uint32_t count = 0;
+
+	if (CGGetDisplaysWithPoint(CGPointMake((long)point.x, (long)point.y),
+	                           0, NULL, &count) != kCGErrorSuccess) {
+		return false;
+	}
+
+	return count > 0;
+#elif defined(USE_X11)
+	/* The root window is the virtual desktop, the points between
+	 * the monitors are in it (and read as black). */
+	Display *display = XGetMainDisplay();
+	int screen;
+
+	if (display == NULL) return false;
+	screen = DefaultScreen(display);
+
+	return (long)point.x >= 0 && (long)point.y >= 0 &&
+	       (long)point.x < DisplayWidth(display, screen) &&
+	       (long)point.y < DisplayHeight(display, screen);
+#elif defined(IS_WINDOWS)
+	POINT p;
+	p.x = (LONG)(long)point.x;
+	p.y = (LONG)(long)point.y;
+
+	return MonitorFromPoint(p, MONITOR_DEFAULTTONULL) != NULL;
+#endif
+}
@@ -5,6 +5,12 @@
 #include "../base/types.h"
 #include "../base/MMBitmap_c.h"
 
+#if defined(_MSC_VER)
+	#include "../base/ms_stdbool.h"
+#else
+	#include <stdbool.h>
+#endif
+
 #ifdef __cplusplus
 extern "C"
 {
@@ -14,6 +20,18 @@ extern "C"
  * caller), or NULL on error. */
 MMBitmapRef copyMMBitmapFromDisplayInRect(MMRect rect);
 
+/* Returns a raw bitmap of screengrab of the virtual desktop (all displays)
+ * rect, the origin may be negative; or NULL on error. */
+MMBitmapRef copyMMBitmapFromVirtualRect(MMSignedRect rect);
+
+/* Enables or disables the MIT-SHM capture (X11 only, enabled by default);
+ * the captures fall back to XGetImage if it is unavailable. */
+void setXShmEnabled(bool enabled);
+
+/* Returns whether the MIT-SHM capture is enabled and available, false if
+ * not X11. */
+bool xShmAvailable(void);
+
 #ifdef __cplusplus
 }
 #endif
@@ -10,6 +10,11 @@
 #elif defined(USE_X11)
 	#include <X11/Xlib.h>
 	#include <X11/Xutil.h>
+	#include <X11/extensions/XShm.h>
+	#include <sys/ipc.h>
+	#include <sys/shm.h>
+	#include <pthread.h>
+	#include <string.h>
 	#include "../base/xdisplay_c.h"
 #elif defined(IS_WINDOWS)
 	// #include "windows.h"
@@ -17,26 +22,19 @@
 	#include <string.h>
 #endif
 
-MMBitmapRef copyMMBitmapFromDisplayInRect(MMRect rect){
 #if defined(IS_MACOSX)
-
+/* Returns the bitmap of the image and releases the image. */
+static MMBitmapRef copyMMBitmapFromCGImage(CGImageRef image){
 	MMBitmapRef bitmap = NULL;
 	uint8_t *buffer = NULL;
 	size_t bufferSize = 0;
 
-	CGDirectDisplayID displayID = CGMainDisplayID();
-
-	CGImageRef image = CGDisplayCreateImageForRect(displayID,
-		CGRectMake(rect.origin.x,
-			rect.origin.y,
-			rect.size.width,
-			rect.size.height));
-
-	if (!image) { return NULL; }
-
 	CFDataRef imageData = CGDataProviderCopyData(CGImageGetDataProvider(image));
 
-	if (!imageData) { return NULL; }
+	if (!imageData) {
+		CGImageRelease(image);
+		return NULL;
+	}
 
 	bufferSize = CFDataGetLength(imageData);
 	buffer = malloc(bufferSize);
@@ -55,32 +53,242 @@ MMBitmapRef copyMMBitmapFromDisplayInRect(MMRect rect){
 	CGImageRelease(image);
 
 	return bitmap;
+}
 #elif defined(USE_X11)
-	MMBitmapRef bitmap;
+/* The MIT-SHM capture state, guarded by xshmLock. The display is kept
+ * open, the segment is grown to the largest capture and reused. */
+static pthread_mutex_t xshmLock = PTHREAD_MUTEX_INITIALIZER;
+static bool xshmEnabled = true;
+static int xshmState = 0; /* 0 unknown, 1 available, -1 unavailable */
+static int xshmRegistered = 0;
+static int xshmError = 0;
+static Display *xshmDisplay = NULL;
+static XErrorHandler xshmPrevHandler = NULL;
+static size_t xshmSize = 0; /* The segment bytes, 0 if none. */
+static XShmSegmentInfo xshmInfo;
+
+/* The rects of fewer pixels, e.g. the 1x1 of GetPixelColor, are captured
+ * by XGetImage on the shm display, the round trip is the same. */
+#define XSHM_MIN_AREA 1024
+
+/* Records the errors of the shm display, the others are passed to the
+ * previous handler. It is installed once, XSetErrorHandler is process
+ * global and would race with the other Xlib users. */
+static int xshmErrorHandler(Display *display, XErrorEvent *event){
+	if (display != NULL && display == xshmDisplay) {
+		xshmError = event->error_code;
+		return 0;
+	}
+
+	return xshmPrevHandler != NULL ? xshmPrevHandler(display, event) : 0;
+}
+
+/* Returns the bitmap of the XImage, the image data is taken. */
+static MMBitmapRef copyMMBitmapFromXImage(XImage *image, size_t w, size_t h){
+	MMBitmapRef bitmap = createMMBitmap((uint8_t *)image->data,
+	                                    w,
+	                                    h,
+	                                    (size_t)image->bytes_per_line,
+	                                    (uint8_t)image->bits_per_pixel,
+	                                    (uint8_t)image->bits_per_pixel / 8);
+	image->data = NULL; /* Steal ownership of bitmap data so we don't have to
+	                     * copy it. */
+	XDestroyImage(image);
+
+	return bitmap;
+}
+
+/* Detaches and frees the segment. */
+static void xshmDestroySegment(void){
+	if (xshmSize == 0) return;
+
+	XShmDetach(xshmDisplay, &xshmInfo);
+	XSync(xshmDisplay, False);
+	shmdt(xshmInfo.shmaddr);
+	xshmSize = 0;
+}
+
+/* Frees the segment and closes the display, the next capture probes the
+ * shm again. */
+static void xshmClose(void){
+	if (xshmDisplay != NULL) {
+		xshmDestroySegment();
+		XCloseDisplay(xshmDisplay);
+		xshmDisplay = NULL;
+	}
+	xshmState = 0;
+}
+
+static void xshmCleanup(void){
+	pthread_mutex_lock(&xshmLock);
+	xshmClose();
+	pthread_mutex_unlock(&xshmLock);
+}
+
+/* Marks the shm unavailable, e.g. a remote display. */
+static void xshmUnavailable(void){
+	xshmClose();
+	xshmState = -1;
+}
+
+/* Opens the shm display, returns false if the shm is unavailable;
+ * the lock must be held. */
+static bool xshmOpen(void){
+	if (!xshmEnabled || xshmState < 0) return false;
+	if (xshmDisplay != NULL) return true;
+
+	xshmDisplay = XOpenDisplay(NULL);
+	if (xshmDisplay == NULL || !XShmQueryExtension(xshmDisplay)) {
+		xshmUnavailable();
+		return false;
+	}
+
+	if (!xshmRegistered) {
+		xshmPrevHandler = XSetErrorHandler(xshmErrorHandler);
+		atexit(&xshmCleanup);
+		xshmRegistered = 1;
+	}
+
+	return true;
+}
+
+/* Grows the segment to at least size bytes, it is never shrunk; returns
+ * false if the shm is unavailable or failed. The lock must be held. */
+static bool xshmSegment(size_t size){
+	XShmSegmentInfo info;
+
+	if (!xshmOpen()) return false;
+	if (xshmSize >= size) return true;
+
+	/* The size may exceed the shmmax, the old segment is kept then and
+	 * the larger rect is captured by XGetImage. */
+	info.shmid = shmget(IPC_PRIVATE, size, IPC_CREAT | 0600);
+	if (info.shmid < 0) return false;
+
+	info.shmaddr = shmat(info.shmid, NULL, 0);
+	info.readOnly = False;
+	if (info.shmaddr == (char *)-1) {
+		shmctl(info.shmid, IPC_RMID, NULL);
+		return false;
+	}
+
+	/* The server can not attach the segment of a remote client. */
+	xshmError = 0;
+	XShmAttach(xshmDisplay, &info);
+	XSync(xshmDisplay, False);
+
+	/* Freed once detached, even if the process crashes. */
+	shmctl(info.shmid, IPC_RMID, NULL);
+
+	if (xshmError) {
+		shmdt(info.shmaddr);
+		xshmUnavailable();
+		return false;
+	}
+
+	xshmDestroySegment();
+	xshmInfo = info;
+	xshmSize = size;
+	xshmState = 1;
+
+	return true;
+}
+
+/* Returns the bitmap of the root window rect by XShmGetImage, or NULL;
+ * done is set if the shm display captured, the NULL is the failure then,
+ * e.g. the rect out of the screen. */
+static MMBitmapRef copyMMBitmapFromRootWindowShm(int x, int y, size_t w, size_t h,
+                                                 bool *done){
+	MMBitmapRef bitmap = NULL;
+	XImage *image;
+	int screen;
+
+	pthread_mutex_lock(&xshmLock);
+	if (!xshmOpen()) {
+		pthread_mutex_unlock(&xshmLock);
+		return NULL;
+	}
+
+	xshmError = 0;
+	if (w * h < XSHM_MIN_AREA) {
+		*done = true;
+		image = XGetImage(xshmDisplay, XDefaultRootWindow(xshmDisplay),
+		                  x, y, (unsigned int)w, (unsigned int)h,
+		                  AllPlanes, ZPixmap);
+		if (image != NULL) {
+			bitmap = copyMMBitmapFromXImage(image, w, h);
+		}
+
+		pthread_mutex_unlock(&xshmLock);
+		return bitmap;
+	}
+
+	/* The image of the rect size over the shared segment. */
+	screen = DefaultScreen(xshmDisplay);
+	image = XShmCreateImage(xshmDisplay,
+	                        DefaultVisual(xshmDisplay, screen),
+	                        (unsigned int)DefaultDepth(xshmDisplay, screen),
+	                        ZPixmap, NULL, &xshmInfo,
+	                        (unsigned int)w, (unsigned int)h);
+
+	if (image != NULL && xshmSegment((size_t)image->bytes_per_line * h)) {
+		image->data = xshmInfo.shmaddr;
+		*done = true;
+
+		if (XShmGetImage(xshmDisplay, XDefaultRootWindow(xshmDisplay),
+		                 image, x, y, AllPlanes) && !xshmError) {
+			/* The segment is reused, copy the frame. */
+			size_t size = (size_t)image->bytes_per_line * h;
+			uint8_t *buffer = malloc(size);
+
+			if (buffer != NULL) {
+				memcpy(buffer, image->data, size);
+				bitmap = createMMBitmap(buffer,
+				                        w,
+				                        h,
+				                        (size_t)image->bytes_per_line,
+				                        (uint8_t)image->bits_per_pixel,
+				                        (uint8_t)image->bits_per_pixel / 8);
+			}
+		}
+	}
+	if (image != NULL) {
+		/* The data is the segment, only the image is freed. */
+		image->data = NULL;
+		XDestroyImage(image);
+	}
+
+	pthread_mutex_unlock(&xshmLock);
+
+	return bitmap;
+}
 
+/* Returns the bitmap of the root window rect, in the root coordinates. */
+static MMBitmapRef copyMMBitmapFromRootWindow(int x, int y, size_t w, size_t h){
+	bool done = false;
+	MMBitmapRef bitmap = copyMMBitmapFromRootWindowShm(x, y, w, h, &done);
+	if (bitmap != NULL || done) return bitmap;
+
+	/* The XGetImage fallback, the shm is unavailable or too small. */
 	Display *display = XOpenDisplay(NULL);
+	if (display == NULL) return NULL;
+
 	XImage *image = XGetImage(display,
 	                          XDefaultRootWindow(display),
-	                          (int)rect.origin.x,
-	                          (int)rect.origin.y,
-	                          (unsigned int)rect.size.width,
-	                          (unsigned int)rect.size.height,
+	                          x,
+	                          y,
+	                          (unsigned int)w,
+	                          (unsigned int)h,
 	                          AllPlanes, ZPixmap);
 	XCloseDisplay(display);
 	if (image == NULL) return NULL;
 
-	bitmap = createMMBitmap((uint8_t *)image->data,
-	                        rect.size.width,
-	                        rect.size.height,
-	                        (size_t)image->bytes_per_line,
-	                        (uint8_t)image->bits_per_pixel,
-	                        (uint8_t)image->bits_per_pixel / 8);
-	image->data = NULL; /* Steal ownership of bitmap data so we don't have to
-	                     * copy it. */
-	XDestroyImage(image);
-
-	return bitmap;
+	return copyMMBitmapFromXImage(image, w, h);
+}
 #elif defined(IS_WINDOWS)
+/* Returns the bitmap of the screen DC rect, the origin is the top left
+ * of the primary display and may be negative. */
+static MMBitmapRef copyMMBitmapFromScreenDC(int x, int y, size_t w, size_t h){
 	MMBitmapRef bitmap;
 	void *data;
 	HDC screen = NULL, screenMem = NULL;
@@ -89,12 +297,12 @@ MMBitmapRef copyMMBitmapFromDisplayInRect(MMRect rect){
 
 	/* Initialize bitmap info. */
 	bi.bmiHeader.biSize = sizeof(bi.bmiHeader);
-   	bi.bmiHeader.biWidth = (long)rect.size.width;
-   	bi.bmiHeader.biHeight = -(long)rect.size.height; /* Non-cartesian, please */
+   	bi.bmiHeader.biWidth = (long)w;
+   	bi.bmiHeader.biHeight = -(long)h; /* Non-cartesian, please */
    	bi.bmiHeader.biPlanes = 1;
    	bi.bmiHeader.biBitCount = 32;
    	bi.bmiHeader.biCompression = BI_RGB;
-   	bi.bmiHeader.biSizeImage = (DWORD)(4 * rect.size.width * rect.size.height);
+   	bi.bmiHeader.biSizeImage = (DWORD)(4 * w * h);
 	bi.bmiHeader.biXPelsPerMeter = 0;
 	bi.bmiHeader.biYPelsPerMeter = 0;
 	bi.bmiHeader.biClrUsed = 0;
@@ -112,11 +320,11 @@ MMBitmapRef copyMMBitmapFromDisplayInRect(MMRect rect){
 	    !BitBlt(screenMem,
 	            (int)0,
 	            (int)0,
-	            (int)rect.size.width,
-	            (int)rect.size.height,
+	            (int)w,
+	            (int)h,
 				screen,
-				rect.origin.x,
-				rect.origin.y,
+				x,
+				y,
 				SRCCOPY)) {
 
 		/* Error copying data. */
@@ -128,9 +336,9 @@ MMBitmapRef copyMMBitmapFromDisplayInRect(MMRect rect){
 	}
 
 	bitmap = createMMBitmap(NULL,
-	                        rect.size.width,
-	                        rect.size.height,
-	                        4 * rect.size.width,
+	                        w,
+	                        h,
+	                        4 * w,
 	                        (uint8_t)bi.bmiHeader.biBitCount,
 	                        4);
 
@@ -145,5 +353,69 @@ MMBitmapRef copyMMBitmapFromDisplayInRect(MMRect rect){
 	DeleteDC(screenMem);
 
 	return bitmap;
+}
+#endif
+
+MMBitmapRef copyMMBitmapFromDisplayInRect(MMRect rect){
+#if defined(IS_MACOSX)
+	CGDirectDisplayID displayID = CGMainDisplayID();
+
+	CGImageRef image = CGDisplayCreateImageForRect(displayID,
+		CGRectMake(rect.origin.x,
+			rect.origin.y,
+			rect.size.width,
+			rect.size.height));
+
+	if (!image) { return NULL; }
+
+	return copyMMBitmapFromCGImage(image);
+#elif defined(USE_X11)
+	return copyMMBitmapFromRootWindow((int)rect.origin.x, (int)rect.origin.y,
+	                                  rect.size.width, rect.size.height);
+#elif defined(IS_WINDOWS)
+	return copyMMBitmapFromScreenDC((int)rect.origin.x, (int)rect.origin.y,
+	                                rect.size.width, rect.size.height);
+#endif
+}
+
+MMBitmapRef copyMMBitmapFromVirtualRect(MMSignedRect rect){
+#if defined(IS_MACOSX)
+	CGImageRef image = CGWindowListCreateImage(
+		CGRectMake(rect.x, rect.y, rect.width, rect.height),
+		kCGWindowListOptionOnScreenOnly, kCGNullWindowID,
+		kCGWindowImageDefault);
+
+	if (!image) { return NULL; }
+
+	return copyMMBitmapFromCGImage(image);
+#elif defined(USE_X11)
+	/* The root window is the virtual desktop. */
+	return copyMMBitmapFromRootWindow(rect.x, rect.y, rect.width, rect.height);
+#elif defined(IS_WINDOWS)
+	return copyMMBitmapFromScreenDC(rect.x, rect.y, rect.width, rect.height);
+#endif
+}
+
+void setXShmEnabled(bool enabled){
+#if defined(USE_X11)
+	pthread_mutex_lock(&xshmLock);
+	xshmEnabled = enabled;
+	xshmClose();
+	pthread_mutex_unlock(&xshmLock);
+#endif
+}
+
+bool xShmAvailable(void){
+#if defined(USE_X11)
+	bool available;
+
+	pthread_mutex_lock(&xshmLock);
+	if (xshmState == 0) xshmSegment(1);
+	available = xshmEnabled && xshmState > 0;
+	pthread_mutex_unlock(&xshmLock);
+
+	return available;
+#else
+	return false;
 #endif
 }
@@ -59,9 +59,8 @@ func aRobotgo() {
 
 	robotgo.SaveBitmap(bitmap, "test.png", 1)
 
-	var bitmapTest robotgo.Bitmap
-	bitTest := robotgo.OpenBitmap("test.png")
-	bitmapTest = robotgo.ToBitmap(bitTest)
+	bitTest := robotgo.CaptureScreen(10, 20, 30, 40)
+	bitmapTest := robotgo.ToBitmap(bitTest)
 	fmt.Println("...type", reflect.TypeOf(bitTest), reflect.TypeOf(bitmapTest))
 
 	// robotgo.MouseClick()
@@ -0,0 +1,84 @@
+// Copyright 2016 The go-vgo Project Developers. See the COPYRIGHT
+// file at the top-level directory of this distribution and at
+// https://github.com/go-vgo/robotgo/blob/master/LICENSE
+//
+// Licensed under the Apache License, Version 2.0 <LICENSE-APACHE or
+// http://www.apache.org/licenses/LICENSE-2.0> or the MIT license
+// <LICENSE-MIT or http://opensource.org/licenses/MIT>, at your
+// option. This file may not be copied, modified, or distributed
+// except according to those terms.
+
+package robotgo
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestWaitPoll(t *testing.T) {
+	n := 0
+	err := waitPoll(context.Background(), &WaitOptions{Interval: time.Millisecond}, func() bool {
+		n++
+		return n == 3
+	})
+	if err != nil || n != 3 {
+		t.Errorf("want nil after 3 polls, got %v after %d", err, n)
+	}
+
+	// the first poll is not delayed by the interval
+	start := time.Now()
+	err = waitPoll(context.Background(), &WaitOptions{Interval: time.Hour},
+		func() bool { return true })
+	if err != nil || time.Since(start) > time.Second {
+		t.Errorf("the first poll should run at once, got %v", err)
+	}
+}
+
+func TestWaitPollTimeout(t *testing.T) {
+	n := 0
+	start := time.Now()
+	err := waitPoll(context.Background(), &WaitOptions{
+		Interval: 5 * time.Millisecond, Timeout: 50 * time.Millisecond,
+	}, func() bool {
+		n++
+		return false
+	})
+
+	if err != context.DeadlineExceeded {
+		t.Errorf("want DeadlineExceeded, got %v", err)
+	}
+	if d := time.Since(start); d < 50*time.Millisecond || d > 5*time.Second {
+		t.Errorf("the timeout took %v", d)
+	}
+	if n < 2 {
+		t.Errorf("want several polls, got %d", n)
+	}
+}
+
+func TestWaitPollCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	err := waitPoll(ctx, nil, func() bool {
+		called = true
+		return true
+	})
+	if err != context.Canceled || called {
+		t.Errorf("want Canceled without a poll, got %v, %v", err, called)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	n := 0
+	err = waitPoll(ctx, &WaitOptions{Interval: time.Millisecond}, func() bool {
+		n++
+		if n == 2 {
+			cancel()
+		}
+		return false
+	})
+	if err != context.Canceled || n != 2 {
+		t.Errorf("want Canceled after 2 polls, got %v after %d", err, n)
+	}
+}